@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"minesweeper-bot/swagger"
+	"sort"
+	"strconv"
+)
+
+// boardAnalysis is what the solver can tell about a board without opening anything.
+type boardAnalysis struct {
+	// Safe are the unknown cells certain not to hold a mine, Mines the ones certain to.
+	Safe  []location
+	Mines []location
+	// Probabilities is the chance of a mine for every cell still unknown, the certain ones included.
+	Probabilities map[location]float64
+	// Recommended is the cell the full strategy would open next: a safe one if there's any,
+	// otherwise the least risky guess. It's nil when there's nothing left to open, or nothing to
+	// go on: no number next to any unknown cell and no mine count.
+	Recommended *plannedMove
+}
+
+// analyzeBoard runs every deduction of the full strategy on a board and works out the mine
+// probabilities of the cells it can't settle. game isn't modified. Cells are "?" for unknown,
+// "*" or "F" for a mine and a digit for an open cell; a MinesCount of 0 means the total is
+// unknown.
+func analyzeBoard(game swagger.Game) (boardAnalysis, error) {
+	if err := validateBoard(game); err != nil {
+		return boardAnalysis{}, err
+	}
+	game.BoardState = append([]string(nil), game.BoardState...)
+	gameInfo := newGameInfo(game)
+	unknownBefore := gameInfo.grid.unknown.members()
+	// a board that no placement of mines fits would get confident nonsense from the deductions
+	components := enumerateComponents(gameInfo.collectConstraints(), len(gameInfo.grid.cells))
+	if _, ways := gameInfo.componentProbabilities(components, gameInfo.queued); math.IsInf(ways, -1) {
+		if game.MinesCount > 0 {
+			return boardAnalysis{}, fmt.Errorf("no placement of %d mines fits the numbers", game.MinesCount)
+		}
+		return boardAnalysis{}, fmt.Errorf("no placement of mines fits the numbers")
+	}
+
+	gameInfo.addFullyRevealedLocations()
+	gameInfo.deduceUntilStuck(game.MinesCount > 0)
+
+	analysis := boardAnalysis{
+		Safe:          append([]location(nil), gameInfo.cellsToOpen...),
+		Probabilities: make(map[location]float64),
+	}
+	for offset, probability := range gameInfo.mineProbabilities() {
+		if probability != noProbability {
+			analysis.Probabilities[gameInfo.grid.location(offset)] = probability
+		}
+	}
+	for _, offset := range unknownBefore {
+		if gameInfo.grid.cells[offset] == gridBomb {
+			loc := gameInfo.grid.location(offset)
+			analysis.Mines = append(analysis.Mines, loc)
+			analysis.Probabilities[loc] = 1
+		}
+	}
+	for _, loc := range analysis.Safe {
+		analysis.Probabilities[loc] = 0
+	}
+	sortLocations(analysis.Safe)
+	sortLocations(analysis.Mines)
+
+	if len(analysis.Safe) > 0 {
+		analysis.Recommended = &plannedMove{Cell: analysis.Safe[0]}
+	} else if loc, risk, err := gameInfo.findLeastRiskyCell(); err == nil {
+		analysis.Recommended = &plannedMove{Cell: loc, Guess: true, Risk: risk}
+	}
+	return analysis, nil
+}
+
+func validateBoard(game swagger.Game) error {
+	if game.BoardWidth <= 0 || game.BoardHeight <= 0 {
+		return fmt.Errorf("board must be at least 1x1, got %dx%d", game.BoardWidth, game.BoardHeight)
+	}
+	if len(game.BoardState) != int(game.BoardWidth)*int(game.BoardHeight) {
+		return fmt.Errorf("a %dx%d board has %d cells, got %d", game.BoardWidth, game.BoardHeight,
+			game.BoardWidth*game.BoardHeight, len(game.BoardState))
+	}
+	if game.MinesCount < 0 || int(game.MinesCount) > len(game.BoardState) {
+		return fmt.Errorf("can't fit %d mines into %d cells", game.MinesCount, len(game.BoardState))
+	}
+	for offset, cellState := range game.BoardState {
+		if cellState == "?" || cellState == "*" || cellState == flaggedCell {
+			continue
+		}
+		if count, err := strconv.Atoi(cellState); err != nil || count < 0 || count > 8 {
+			return fmt.Errorf("cell (%d, %d) is %q, expected \"?\", \"*\", \"F\" or 0 to 8",
+				offset%int(game.BoardWidth), offset/int(game.BoardWidth), cellState)
+		}
+	}
+
+	g := newGrid(&game)
+	if game.MinesCount > 0 && g.bombs.count() > int(game.MinesCount) {
+		return fmt.Errorf("%d mines on the board, but it only has %d", g.bombs.count(), game.MinesCount)
+	}
+	for offset := range g.cells {
+		count, ok := g.number(offset)
+		if !ok {
+			continue
+		}
+		loc := g.location(offset)
+		if bombs := g.countAround(offset, gridBomb); count < bombs {
+			return fmt.Errorf("cell (%d, %d) is a %d with %d mines around", loc.X, loc.Y, count, bombs)
+		} else if room := bombs + g.countAround(offset, gridUnknown); count > room {
+			return fmt.Errorf("cell (%d, %d) is a %d, but only %d of its neighbours can be mines", loc.X, loc.Y, count, room)
+		}
+	}
+	return nil
+}
+
+func sortLocations(locs []location) {
+	sort.Slice(locs, func(i, j int) bool { return locs[i].less(locs[j]) })
+}
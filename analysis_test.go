@@ -0,0 +1,180 @@
+package main
+
+import (
+	"math"
+	"minesweeper-bot/swagger"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testBoard is a board with the given rows, separated by "/", of one character per cell.
+func testBoard(minesCount int32, board string) swagger.Game {
+	rows := strings.Split(board, "/")
+	game := swagger.Game{BoardWidth: int32(len(rows[0])), BoardHeight: int32(len(rows)), MinesCount: minesCount}
+	for _, row := range rows {
+		for _, cell := range row {
+			game.BoardState = append(game.BoardState, string(cell))
+		}
+	}
+	return game
+}
+
+func TestAnalyzeBoard(t *testing.T) {
+	tests := []struct {
+		name       string
+		minesCount int32
+		board      string
+		safe       []location
+		mines      []location
+		// probabilities of some of the cells left unknown
+		probabilities map[location]float64
+		// unestimated are unknown cells nothing can be said about
+		unestimated []location
+	}{
+		{
+			name:  "1-2-1",
+			board: "???/121",
+			safe:  []location{{1, 0}},
+			mines: []location{{0, 0}, {2, 0}},
+		},
+		{
+			name:          "1-1 against a wall",
+			board:         "????/11??",
+			safe:          []location{{2, 0}, {2, 1}},
+			probabilities: map[location]float64{{0, 0}: 0.5, {1, 0}: 0.5},
+		},
+		{
+			name:          "1-1 against a wall, with the mine count",
+			minesCount:    2,
+			board:         "????/11??",
+			safe:          []location{{2, 0}, {2, 1}},
+			probabilities: map[location]float64{{0, 0}: 0.5, {1, 0}: 0.5, {3, 0}: 0.5, {3, 1}: 0.5},
+		},
+		{
+			name:          "interior only",
+			minesCount:    3,
+			board:         "???/???/???",
+			probabilities: map[location]float64{{0, 0}: 1.0 / 3, {1, 1}: 1.0 / 3, {2, 2}: 1.0 / 3},
+		},
+		{
+			name:          "mines unknown",
+			board:         "?1?1?",
+			probabilities: map[location]float64{{0, 0}: 0.5, {2, 0}: 0.5, {4, 0}: 0.5},
+		},
+		{
+			name:        "mines unknown and no numbers",
+			board:       "???/???",
+			unestimated: []location{{0, 0}, {1, 1}, {2, 1}},
+		},
+		{
+			name:          "mines unknown, interior",
+			board:         "1??/???",
+			probabilities: map[location]float64{{1, 0}: 1.0 / 3, {0, 1}: 1.0 / 3, {1, 1}: 1.0 / 3},
+			unestimated:   []location{{2, 0}, {2, 1}},
+		},
+		{
+			name:       "one mine left",
+			minesCount: 1,
+			board:      "?1?1?",
+			safe:       []location{{0, 0}, {4, 0}},
+			mines:      []location{{2, 0}},
+		},
+		{
+			name:       "two mines left",
+			minesCount: 2,
+			board:      "?1?1?",
+			safe:       []location{{2, 0}},
+			mines:      []location{{0, 0}, {4, 0}},
+		},
+		{
+			name:       "every mine found",
+			minesCount: 1,
+			board:      "*1???",
+			safe:       []location{{2, 0}, {3, 0}, {4, 0}},
+		},
+		{
+			// the two cells next to the numbers hold one mine, so the six cells of the interior
+			// hold the other
+			name:       "mine count and interior",
+			minesCount: 2,
+			board:      "1????/1????",
+			probabilities: map[location]float64{
+				{1, 0}: 0.5, {1, 1}: 0.5, {2, 0}: 1.0 / 6, {4, 1}: 1.0 / 6,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis, err := analyzeBoard(testBoard(tt.minesCount, tt.board))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(analysis.Safe, tt.safe) {
+				t.Errorf("safe cells are %v, want %v", analysis.Safe, tt.safe)
+			}
+			if !reflect.DeepEqual(analysis.Mines, tt.mines) {
+				t.Errorf("mines are %v, want %v", analysis.Mines, tt.mines)
+			}
+			for loc, want := range tt.probabilities {
+				if got, ok := analysis.Probabilities[loc]; !ok || math.Abs(got-want) > 1e-9 {
+					t.Errorf("chance of a mine in %v is %v, want %v", loc, got, want)
+				}
+			}
+			for _, loc := range tt.unestimated {
+				if probability, ok := analysis.Probabilities[loc]; ok {
+					t.Errorf("chance of a mine in %v is %v, want none", loc, probability)
+				}
+			}
+			if analysis.Recommended == nil {
+				if len(tt.probabilities) > 0 || len(tt.safe) > 0 {
+					t.Errorf("nothing recommended")
+				}
+				return
+			}
+			if len(tt.safe) > 0 && analysis.Recommended.Guess {
+				t.Errorf("recommended a guess, %v, with safe cells left", analysis.Recommended.Cell)
+			}
+		})
+	}
+}
+
+// A long frontier with few numbers has far too many assignments to enumerate, so it's only
+// estimated; that has to be quick and must not settle any cell.
+func TestAnalyzeBoardLongFrontier(t *testing.T) {
+	rows := []string{strings.Repeat("?", 40), strings.Repeat("4?", 20), strings.Repeat("?", 40)}
+	analysis, err := analyzeBoard(testBoard(0, strings.Join(rows, "/")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.Safe) > 0 || len(analysis.Mines) > 0 {
+		t.Errorf("an estimate settled cells: safe %v, mines %v", analysis.Safe, analysis.Mines)
+	}
+	for loc, probability := range analysis.Probabilities {
+		if probability <= 0 || probability >= 1 {
+			t.Errorf("chance of a mine in %v is %v", loc, probability)
+		}
+	}
+	if analysis.Recommended == nil || !analysis.Recommended.Guess {
+		t.Errorf("recommended %v, want a guess", analysis.Recommended)
+	}
+}
+
+func TestAnalyzeBoardImpossible(t *testing.T) {
+	tests := []struct {
+		name       string
+		minesCount int32
+		board      string
+	}{
+		{"number bigger than its neighbours", 1, "3?"},
+		{"too many mines around a number", 0, "*1*"},
+		{"more mines found than there are", 1, "*1*/111"},
+		{"number no placement fits", 0, "1?1/?0?"},
+		{"mine count no placement fits", 5, "???/?8?/???"},
+	}
+	for _, tt := range tests {
+		if _, err := analyzeBoard(testBoard(tt.minesCount, tt.board)); err == nil {
+			t.Errorf("%s: %s was analyzed", tt.name, tt.board)
+		}
+	}
+}
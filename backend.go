@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"minesweeper-bot/engine"
+	"minesweeper-bot/swagger"
+	"time"
+)
+
+// gameBackend is whatever the bot plays against: minesweeper-server over HTTP, the in-process
+// engine, or anything else that can start games and accept moves. Errors are gameErrors.
+type gameBackend interface {
+	NewGame(ctx context.Context, request newGameRequest) (swagger.Game, error)
+	Move(ctx context.Context, moveInfo swagger.MoveInfo) (swagger.Game, error)
+	// FirstClick is what the backend's games guarantee about the first cell opened.
+	FirstClick() engine.FirstClick
+}
+
+// newGameRequest describes the game to start.
+type newGameRequest struct {
+	// Seed picks the mine layout: the same seed gives the same board.
+	Seed  int64
+	Board boardSpec
+}
+
+// boardSpec is the size and number of mines of a board. The zero value leaves them to the
+// backend, which hands out intermediate boards.
+type boardSpec struct {
+	// Difficulty is one of engine.DifficultyNames, or empty.
+	Difficulty string
+	// Width, Height and Mines override the difficulty's when they're not 0.
+	Width, Height, Mines int
+}
+
+// engineConfig is the board the local engine should create.
+func (spec boardSpec) engineConfig() (engine.Config, error) {
+	cfg := engine.DefaultConfig()
+	if spec.Difficulty != "" {
+		var err error
+		if cfg, err = engine.Difficulty(spec.Difficulty); err != nil {
+			return engine.Config{}, err
+		}
+	}
+	if spec.Width != 0 {
+		cfg.Width = spec.Width
+	}
+	if spec.Height != 0 {
+		cfg.Height = spec.Height
+	}
+	if spec.Mines != 0 {
+		cfg.Mines = spec.Mines
+	}
+	return cfg, nil
+}
+
+func (spec boardSpec) String() string {
+	if spec.Width == 0 && spec.Height == 0 && spec.Mines == 0 {
+		if spec.Difficulty == "" {
+			return "default"
+		}
+		return spec.Difficulty
+	}
+	cfg, err := spec.engineConfig()
+	if err != nil {
+		return spec.Difficulty
+	}
+	return fmt.Sprintf("%dx%d/%d", cfg.Width, cfg.Height, cfg.Mines)
+}
+
+// backendConfig says which backend to play on and how to reach it.
+type backendConfig struct {
+	name string // http or local
+
+	// for the http backend
+	basePath   string
+	timeout    time.Duration // per attempt of a request, 0 for none
+	maxRetries int
+
+	// firstClick is the rule the local engine follows, or the one the server is known to follow
+	firstClick engine.FirstClick
+}
+
+func newBackend(config backendConfig) (gameBackend, error) {
+	switch config.name {
+	case "http":
+		configuration := swagger.NewConfiguration()
+		configuration.BasePath = config.basePath
+		configuration.Timeout = config.timeout
+		configuration.MaxRetries = config.maxRetries
+		return swaggerBackend{client: swagger.NewAPIClient(configuration), firstClick: config.firstClick}, nil
+	case "local":
+		return localBackend{engine: engine.New(engine.DefaultConfig()), firstClick: config.firstClick}, nil
+	default:
+		return nil, fmt.Errorf("unknown backend %q, expected http or local", config.name)
+	}
+}
+
+// swaggerBackend plays on minesweeper-server through the generated client.
+type swaggerBackend struct {
+	client *swagger.APIClient
+	// firstClick is taken on trust, the server doesn't say
+	firstClick engine.FirstClick
+}
+
+func (b swaggerBackend) NewGame(ctx context.Context, request newGameRequest) (swagger.Game, error) {
+	optionals := map[string]interface{}{
+		"seed": request.Seed,
+	}
+	if request.Board.Difficulty != "" {
+		optionals["difficulty"] = request.Board.Difficulty
+	}
+	if request.Board.Width != 0 {
+		optionals["width"] = int32(request.Board.Width)
+	}
+	if request.Board.Height != 0 {
+		optionals["height"] = int32(request.Board.Height)
+	}
+	if request.Board.Mines != 0 {
+		optionals["mines"] = int32(request.Board.Mines)
+	}
+	game, response, err := b.client.DefaultApi.NewgamePost(ctx, optionals)
+	return game, classifySwaggerError("newgame", err, response)
+}
+
+func (b swaggerBackend) Move(ctx context.Context, moveInfo swagger.MoveInfo) (swagger.Game, error) {
+	game, response, err := b.client.DefaultApi.MovePost(ctx, moveInfo)
+	return game, classifySwaggerError("move", err, response)
+}
+
+func (b swaggerBackend) FirstClick() engine.FirstClick {
+	return b.firstClick
+}
+
+// localBackend plays on the in-process engine, no server needed.
+type localBackend struct {
+	engine     *engine.Engine
+	firstClick engine.FirstClick
+}
+
+func (b localBackend) NewGame(ctx context.Context, request newGameRequest) (swagger.Game, error) {
+	cfg, err := request.Board.engineConfig()
+	if err != nil {
+		return swagger.Game{}, classifyEngineError("newgame", err)
+	}
+	cfg.FirstClick = b.firstClick
+	game, err := b.engine.NewCustomGame(request.Seed, cfg)
+	return game, classifyEngineError("newgame", err)
+}
+
+func (b localBackend) Move(ctx context.Context, moveInfo swagger.MoveInfo) (swagger.Game, error) {
+	game, err := b.engine.Move(moveInfo)
+	return game, classifyEngineError("move", err)
+}
+
+func (b localBackend) FirstClick() engine.FirstClick {
+	return b.firstClick
+}
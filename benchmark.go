@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"minesweeper-bot/engine"
+	statistics "minesweeper-bot/stats"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+func benchmarkCommand(args []string) error {
+	flags := newFlagSet("benchmark")
+	backendOptions := addBackendFlags(flags)
+	difficultyList := flags.String("difficulties", strings.Join(engine.DifficultyNames(), ","), "comma separated difficulties to play")
+	strategyName := flags.String("strategy", defaultStrategyName, fmt.Sprintf("solver to play with, one of %v", strategyNames()))
+	gamesToPlay := flags.Int("games", 1000, "number of games to play on every difficulty")
+	concurrency := flags.Int("concurrency", runtime.NumCPU(), "number of games to play at the same time")
+	seed := flags.Int64("seed", time.Now().UnixNano(), "seed of the first game on every difficulty, game N is played with seed+N")
+	openingList := flags.String("openings", openingCentre, fmt.Sprintf("comma separated openings to play every difficulty with, each one of %v or a cell as \"x,y\"", openingKinds))
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	openings := make([]opening, 0)
+	for _, text := range splitOpenings(*openingList) {
+		first, err := parseOpening(text)
+		if err != nil {
+			return usageError{err.Error()}
+		}
+		openings = append(openings, first)
+	}
+	cases := make([]benchmarkCase, 0)
+	for _, name := range strings.Split(*difficultyList, ",") {
+		if _, err := engine.Difficulty(name); err != nil {
+			return usageError{err.Error()}
+		}
+		for _, first := range openings {
+			c := benchmarkCase{board: boardSpec{Difficulty: name}, opening: first}
+			if err := backendOptions.checkOpening(c.opening, c.board); err != nil {
+				return err
+			}
+			cases = append(cases, c)
+		}
+	}
+	if *gamesToPlay < 1 {
+		return usageError{"-games must be at least 1"}
+	}
+	backend, err := backendOptions.newBackend()
+	if err != nil {
+		return err
+	}
+	strategy, err := strategyByName(*strategyName)
+	if err != nil {
+		return usageError{err.Error()}
+	}
+
+	ctx, stop := interruptibleContext(abandonedGames)
+	defer stop()
+
+	results := make([]*runStats, 0, len(cases))
+	for _, c := range cases {
+		fmt.Printf("playing %d games on %s boards, opening %s\n", *gamesToPlay, c.board, c.opening)
+		gamesRunner := runner{backend: backend, strategy: strategy, workers: *concurrency, board: c.board, opening: c.opening}
+		results = append(results, gamesRunner.run(ctx, *gamesToPlay, *seed, nil))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	printBenchmark(os.Stdout, cases, results)
+	if ctx.Err() != nil {
+		return fmt.Errorf("interrupted, the results above are incomplete")
+	}
+	return nil
+}
+
+// benchmarkCase is a board and the opening it's played with.
+type benchmarkCase struct {
+	board   boardSpec
+	opening opening
+}
+
+// printBenchmark prints a line of statistics for every board and opening that was played.
+func printBenchmark(w io.Writer, cases []benchmarkCase, results []*runStats) {
+	_, _ = fmt.Fprintln(w)
+	for i, stats := range results {
+		cfg, _ := cases[i].board.engineConfig()
+		winRate, interval := stats.winRate()
+		progress := statistics.Describe(stats.minesFoundPercentages)
+		guesses := statistics.Describe(stats.guessesPerGame)
+		averageDuration := time.Duration(0)
+		if stats.finishedGames() > 0 {
+			averageDuration = stats.duration / time.Duration(stats.finishedGames())
+		}
+		_, _ = fmt.Fprintf(w, "%-12s %-7s %2dx%-2d %3d mines: win rate %5.1f%% (95%% CI %.1f%% to %.1f%%), average progress %5.1f%%, %.2f guesses and %v per game, %d finished games\n",
+			cases[i].board, cases[i].opening, cfg.Width, cfg.Height, cfg.Mines, winRate*100, interval.Low*100, interval.High*100,
+			progress.Mean*100, guesses.Mean, averageDuration.Round(time.Microsecond), stats.finishedGames())
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import "minesweeper-bot/swagger"
+
+// planActions turns the cells a strategy wants opened into the moves to send. With flagMines,
+// every bomb found that isn't flagged yet gets flagged first, so the server's board shows them.
+// With chord, numbered cells whose bombs are all known are chorded, flagging the bombs first,
+// wherever that takes fewer requests than opening their unknown neighbours one by one. Guesses
+// are always sent as they are.
+func (game *gameInformation) planActions(moves []plannedMove, flagMines, chord bool) []plannedMove {
+	if !flagMines && !chord {
+		return moves
+	}
+	g := &game.grid
+	result := make([]plannedMove, 0, len(moves))
+	flags := newBitset(len(g.cells)) // flagged on the server, or about to be
+	copy(flags, game.flagged)
+	flag := func(offset int) {
+		if !flags.has(offset) {
+			flags.add(offset)
+			result = append(result, plannedMove{Cell: g.location(offset), Action: swagger.MoveActionFlag})
+		}
+	}
+
+	if flagMines {
+		for _, offset := range game.bombs.members() {
+			flag(offset)
+		}
+	}
+	if !chord {
+		return append(result, moves...)
+	}
+
+	toOpen := newBitset(len(g.cells))
+	for _, planned := range moves {
+		if !planned.Guess && planned.Action == "" {
+			toOpen.add(g.offset(planned.Cell))
+		}
+	}
+	game.addFullyRevealedLocations()
+	for _, offset := range game.frontier.members() {
+		count, _ := g.number(offset)
+		if g.countAround(offset, gridBomb) != count { // chording would open cells that may hold a bomb
+			continue
+		}
+
+		opened, missingFlags := 0, 0
+		for _, n := range g.neighbours[offset] {
+			if g.cells[n] == gridUnknown && toOpen.has(n) {
+				opened++
+			} else if g.cells[n] == gridBomb && !flags.has(n) {
+				missingFlags++
+			}
+		}
+		// the flags and the chord itself have to save at least one request
+		if missingFlags+1 >= opened {
+			continue
+		}
+
+		for _, n := range g.neighbours[offset] {
+			switch g.cells[n] {
+			case gridBomb:
+				flag(n)
+			case gridUnknown:
+				toOpen.remove(n)
+			}
+		}
+		result = append(result, plannedMove{Cell: g.location(offset), Action: swagger.MoveActionChord})
+	}
+
+	for _, planned := range moves {
+		if planned.Guess || planned.Action != "" || toOpen.has(g.offset(planned.Cell)) {
+			result = append(result, planned)
+		}
+	}
+	return result
+}
+
+// isPlayed tells if a planned move has nothing left to do: its cell is already open, its bomb
+// already flagged, or there's nothing left around it to chord.
+func (game *gameInformation) isPlayed(planned plannedMove) bool {
+	offset := game.grid.offset(planned.Cell)
+	switch planned.Action {
+	case swagger.MoveActionFlag:
+		return game.flagged.has(offset)
+	case swagger.MoveActionChord:
+		return game.grid.countAround(offset, gridUnknown) == 0
+	default:
+		return !game.isUnknown(planned.Cell)
+	}
+}
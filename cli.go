@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"minesweeper-bot/engine"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// command is a subcommand of the bot, e.g. `minesweeper-bot play -games 100`.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+// commands are listed in `help` in this order. The first one runs when no command is given.
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "play", summary: "play a batch of games and print statistics", run: playCommand},
+		{name: "benchmark", summary: "play the classic difficulties and compare how the solver does on each", run: benchmarkCommand},
+		{name: "compare", summary: "play several strategies on the same boards and compare them", run: compareCommand},
+		{name: "watch", summary: "watch the bot play in the terminal, step through its moves or take over", run: watchCommand},
+		{name: "solve", summary: "print what the solver makes of a board written out as text", run: solveCommand},
+		{name: "serve", summary: "answer HTTP requests for hints on a board, no game server needed", run: serveCommand},
+		{name: "replay", summary: "show a recorded game move by move", run: replayCommand},
+		{name: "stats", summary: "summarize a JSON Lines run, or test if one run beats another", run: statsCommand},
+		{name: "help", summary: "show this help", run: helpCommand},
+	}
+}
+
+// usageError is returned for bad command lines; the process exits with status 2 for those.
+type usageError struct {
+	message string
+}
+
+func (e usageError) Error() string {
+	return e.message
+}
+
+func runCommand(args []string) int {
+	cmd := commands[0]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		found := false
+		for _, c := range commands {
+			if c.name == args[0] {
+				cmd = c
+				found = true
+				break
+			}
+		}
+		if !found {
+			fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+			printUsage(os.Stderr)
+			return 2
+		}
+		args = args[1:]
+	}
+
+	err := cmd.run(args)
+	switch err.(type) {
+	case nil:
+		return 0
+	case usageError:
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	default:
+		if err == flag.ErrHelp {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "usage: %s <command> [flags]\n\ncommands:\n", programName())
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(w, "\nrun %s <command> -h to see the flags of a command\n", programName())
+}
+
+func programName() string {
+	return filepath.Base(os.Args[0])
+}
+
+func helpCommand(args []string) error {
+	printUsage(os.Stdout)
+	return nil
+}
+
+// newFlagSet creates the flag set of a command. Parse errors are returned rather than exiting,
+// so runCommand decides on the exit status.
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s %s [flags]\n", programName(), name)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// backendFlags are the flags of every command that plays games.
+type backendFlags struct {
+	backend    *string
+	basePath   *string
+	timeout    *time.Duration
+	maxRetries *int
+	firstClick *string
+}
+
+func addBackendFlags(flags *flag.FlagSet) backendFlags {
+	return backendFlags{
+		backend:    flags.String("backend", "http", "where to play: http (minesweeper-server) or local (in-process engine)"),
+		basePath:   flags.String("server", "http://localhost:3000", "minesweeper-server base path, for the http backend"),
+		timeout:    flags.Duration("timeout", 30*time.Second, "time limit of every request to the server, 0 for none"),
+		maxRetries: flags.Int("retries", 3, "how many times to retry a request when the server can't be reached or answers 502/503"),
+		firstClick: flags.String("first-click", string(engine.FirstClickCentre), fmt.Sprintf("where mines can't be on the first click, one of %v. The local engine follows it, for the http backend it's what the server is known to do", engine.FirstClickNames())),
+	}
+}
+
+func (f backendFlags) newBackend() (gameBackend, error) {
+	if !contains(engine.FirstClickNames(), *f.firstClick) {
+		return nil, usageError{fmt.Sprintf("unknown first click rule %q, expected one of %v", *f.firstClick, engine.FirstClickNames())}
+	}
+	backend, err := newBackend(backendConfig{
+		name:       *f.backend,
+		basePath:   *f.basePath,
+		timeout:    *f.timeout,
+		maxRetries: *f.maxRetries,
+		firstClick: engine.FirstClick(*f.firstClick),
+	})
+	if err != nil {
+		return nil, usageError{err.Error()}
+	}
+	return backend, nil
+}
+
+// checkOpening returns a usageError if the opening is off the board, when the size of the board
+// is known before the first game: always on the local engine, and on the server when the board
+// flags pick the size. Otherwise it's only found out game by game.
+func (f backendFlags) checkOpening(o opening, board boardSpec) error {
+	if *f.backend != "local" && board.Difficulty == "" && (board.Width == 0 || board.Height == 0) {
+		return nil
+	}
+	cfg, err := board.engineConfig()
+	if err != nil {
+		return usageError{err.Error()}
+	}
+	if cell := o.location(cfg.Width, cfg.Height); cell.X >= cfg.Width || cell.Y >= cfg.Height {
+		return usageError{fmt.Sprintf("opening %s is outside of the %dx%d board", o, cfg.Width, cfg.Height)}
+	}
+	return nil
+}
+
+// boardFlags pick the board to play on.
+type boardFlags struct {
+	difficulty *string
+	width      *int
+	height     *int
+	mines      *int
+}
+
+func addBoardFlags(flags *flag.FlagSet) boardFlags {
+	return boardFlags{
+		difficulty: flags.String("difficulty", "", fmt.Sprintf("board to play on, one of %v. The server's default board if empty", engine.DifficultyNames())),
+		width:      flags.Int("width", 0, "board width, overrides the difficulty's"),
+		height:     flags.Int("height", 0, "board height, overrides the difficulty's"),
+		mines:      flags.Int("mines", 0, "number of mines, overrides the difficulty's"),
+	}
+}
+
+func (f boardFlags) spec() (boardSpec, error) {
+	spec := boardSpec{Difficulty: *f.difficulty, Width: *f.width, Height: *f.height, Mines: *f.mines}
+	if spec.Width < 0 || spec.Height < 0 || spec.Mines < 0 {
+		return boardSpec{}, usageError{"-width, -height and -mines can't be negative"}
+	}
+	cfg, err := spec.engineConfig()
+	if err != nil {
+		return boardSpec{}, usageError{err.Error()}
+	}
+	if cfg.Mines >= cfg.Width*cfg.Height {
+		return boardSpec{}, usageError{fmt.Sprintf("can't fit %d mines on a %dx%d board with a safe first move", cfg.Mines, cfg.Width, cfg.Height)}
+	}
+	return spec, nil
+}
+
+var outputFormats = []string{"text", "jsonl", "csv"}
+
+func playCommand(args []string) error {
+	flags := newFlagSet("play")
+	backendOptions := addBackendFlags(flags)
+	boardOptions := addBoardFlags(flags)
+	gamesToPlay := flags.Int("games", 1000, "number of games to play")
+	concurrency := flags.Int("concurrency", runtime.NumCPU(), "number of games to play at the same time")
+	strategyName := flags.String("strategy", defaultStrategyName, fmt.Sprintf("solver to play with, one of %v", strategyNames()))
+	seed := flags.Int64("seed", time.Now().UnixNano(), "seed of the first game, game N is played with seed+N. Replaying a seed gives the same board, if the backend supports seeds")
+	format := flags.String("format", "text", fmt.Sprintf("output format, one of %v", outputFormats))
+	summaryFile := flags.String("summary-file", "", "write the summary of the run to this file instead. CSV summaries go to stderr by default, since their columns differ from the games'")
+	printBoards := flags.Bool("print-boards", false, "print the board after every move")
+	recordDir := flags.String("record-dir", "", "save a recording of every game into this directory, see the replay command")
+	flagMines := flags.Bool("flag", false, "flag every bomb found, so the server's board shows them")
+	chord := flags.Bool("chord", false, "chord numbers whose bombs are all found, flagging them first, where that takes fewer moves than opening cells one by one")
+	openingText := flags.String("opening", openingCentre, openingUsage)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	first, err := parseOpening(*openingText)
+	if err != nil {
+		return usageError{err.Error()}
+	}
+
+	if *gamesToPlay < 1 {
+		return usageError{"-games must be at least 1"}
+	}
+	if *concurrency < 1 {
+		return usageError{"-concurrency must be at least 1"}
+	}
+	if !contains(outputFormats, *format) {
+		return usageError{fmt.Sprintf("unknown output format %q, expected one of %v", *format, outputFormats)}
+	}
+	backend, err := backendOptions.newBackend()
+	if err != nil {
+		return err
+	}
+	board, err := boardOptions.spec()
+	if err != nil {
+		return err
+	}
+	if err := backendOptions.checkOpening(first, board); err != nil {
+		return err
+	}
+	strategy, err := strategyByName(*strategyName)
+	if err != nil {
+		return usageError{err.Error()}
+	}
+
+	var summaryOut io.Writer = os.Stdout
+	if *format == "csv" {
+		summaryOut = os.Stderr
+	}
+	if *summaryFile != "" {
+		f, err := os.Create(*summaryFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		summaryOut = f
+	}
+
+	gamesRunner := runner{backend: backend, strategy: strategy, workers: *concurrency, flagMines: *flagMines, chord: *chord, board: board, opening: first}
+	if *printBoards {
+		gamesRunner.boardLog = os.Stdout
+	}
+	if *recordDir != "" {
+		if err := os.MkdirAll(*recordDir, 0755); err != nil {
+			return err
+		}
+		gamesRunner.recordDir = *recordDir
+	}
+	if *format == "text" {
+		fmt.Printf("playing %d games, seeds %d to %d\n", *gamesToPlay, *seed, *seed+int64(*gamesToPlay-1))
+	}
+	ctx, stop := interruptibleContext(abandonedGames)
+	defer stop()
+	return playGames(ctx, gamesRunner, *gamesToPlay, *seed, newReporter(*format, os.Stdout, summaryOut))
+}
+
+// interruptibleContext returns a context that's cancelled on the first SIGINT or SIGTERM, so
+// that a run can stop and still report what it has. message tells what stopping abandons. A
+// second signal exits right away, after running cleanup, e.g. to put the terminal back; reports
+// lose nothing but their summary, since every game is written out as it finishes. stop releases
+// the signal handler.
+func interruptibleContext(message string, cleanup ...func()) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-signals:
+		case <-done:
+			return
+		}
+		fmt.Fprintf(os.Stderr, "stopping, %s. Interrupt again to quit right away\n", message)
+		cancel()
+		select {
+		case <-signals:
+			for _, f := range cleanup {
+				f()
+			}
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		close(done)
+		cancel()
+	}
+}
+
+// abandonedGames is the interrupt message of the commands that play games.
+const abandonedGames = "the games in progress are abandoned"
+
+func contains(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	statistics "minesweeper-bot/stats"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+func compareCommand(args []string) error {
+	flags := newFlagSet("compare")
+	backendOptions := addBackendFlags(flags)
+	boardOptions := addBoardFlags(flags)
+	strategyList := flags.String("strategies", strings.Join(strategyNames(), ","), "comma separated strategies to compare. The first one is the reference the others are tested against")
+	gamesToPlay := flags.Int("games", 1000, "number of boards every strategy plays")
+	concurrency := flags.Int("concurrency", runtime.NumCPU(), "number of games to play at the same time")
+	seed := flags.Int64("seed", time.Now().UnixNano(), "seed of the first board, board N is generated with seed+N")
+	showDisagreements := flags.Int("show-disagreements", 20, "how many of the boards where the strategies disagreed to list")
+	openingText := flags.String("opening", openingCentre, openingUsage)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	first, err := parseOpening(*openingText)
+	if err != nil {
+		return usageError{err.Error()}
+	}
+
+	names := strings.Split(*strategyList, ",")
+	if len(names) < 2 {
+		return usageError{"compare needs at least two strategies"}
+	}
+	strategiesToCompare := make([]Strategy, 0, len(names))
+	for _, name := range names {
+		strategy, err := strategyByName(name)
+		if err != nil {
+			return usageError{err.Error()}
+		}
+		strategiesToCompare = append(strategiesToCompare, strategy)
+	}
+	if *gamesToPlay < 1 {
+		return usageError{"-games must be at least 1"}
+	}
+	backend, err := backendOptions.newBackend()
+	if err != nil {
+		return err
+	}
+	board, err := boardOptions.spec()
+	if err != nil {
+		return err
+	}
+	if err := backendOptions.checkOpening(first, board); err != nil {
+		return err
+	}
+
+	ctx, stop := interruptibleContext(abandonedGames)
+	defer stop()
+
+	// every strategy plays the same seeds, so the results can be compared board by board
+	results := make([][]gameResult, len(names))
+	for i, strategy := range strategiesToCompare {
+		fmt.Printf("playing %d games with %s, seeds %d to %d\n", *gamesToPlay, names[i], *seed, *seed+int64(*gamesToPlay-1))
+		gamesRunner := runner{backend: backend, strategy: strategy, workers: *concurrency, board: board, opening: first}
+		gamesRunner.run(ctx, *gamesToPlay, *seed, func(result gameResult, stats *runStats) {
+			results[i] = append(results[i], result)
+		})
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if unpaired := countUnpaired(results); unpaired > 0 {
+		return fmt.Errorf("%d games came back with another seed than the one asked for or none: the backend doesn't honour seeds, so the strategies didn't play the same boards", unpaired)
+	}
+	printTournament(os.Stdout, names, results, *showDisagreements)
+	if ctx.Err() != nil {
+		return fmt.Errorf("interrupted, the results above are incomplete")
+	}
+	return nil
+}
+
+// printTournament prints how every strategy did, tests each of them against the first one, and
+// lists boards where they didn't all end the same way.
+func printTournament(w io.Writer, names []string, results [][]gameResult, showDisagreements int) {
+	_, _ = fmt.Fprintln(w)
+	for i, name := range names {
+		stats := newRunStats()
+		for _, result := range results[i] {
+			stats.add(result)
+		}
+		winRate, interval := stats.winRate()
+		progress := statistics.Describe(stats.minesFoundPercentages)
+		_, _ = fmt.Fprintf(w, "%-12s win rate %5.1f%% (95%% CI %.1f%% to %.1f%%), average progress %5.1f%%, %d finished games\n",
+			name, winRate*100, interval.Low*100, interval.High*100, progress.Mean*100, stats.finishedGames())
+	}
+
+	for i := 1; i < len(names); i++ {
+		if results[i] == nil {
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "\n== %s vs %s\n", names[i], names[0])
+		printComparison(w, names[0], results[0], names[i], results[i])
+	}
+
+	disagreements := findDisagreements(results)
+	_, _ = fmt.Fprintf(w, "\nstrategies disagreed on %d boards\n", len(disagreements))
+	for i, seed := range disagreements {
+		if i == showDisagreements {
+			_, _ = fmt.Fprintf(w, "... and %d more\n", len(disagreements)-showDisagreements)
+			break
+		}
+		outcomes := make([]string, 0, len(names))
+		for j, name := range names {
+			for _, result := range results[j] {
+				if result.Seed == seed {
+					outcomes = append(outcomes, fmt.Sprintf("%s: %s (%d/%d mines)", name, result.Status, result.MinesFound, result.MinesTotal))
+				}
+			}
+		}
+		_, _ = fmt.Fprintf(w, "seed %d: %s\n", seed, strings.Join(outcomes, ", "))
+	}
+}
+
+// countUnpaired counts the games whose board the backend didn't make with the seed asked for.
+// Every strategy is meant to play the same boards, but a server that doesn't know about seeds
+// hands out random ones instead, and comparing them board by board would find differences that
+// are only down to luck.
+func countUnpaired(results [][]gameResult) int {
+	count := 0
+	for _, strategyResults := range results {
+		for _, result := range strategyResults {
+			if result.GameId != "" && result.BoardSeed != result.Seed {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// findDisagreements returns the seeds, in order, of the boards that every strategy finished but
+// not with the same status.
+func findDisagreements(results [][]gameResult) []int64 {
+	statuses := make(map[int64][]string)
+	for _, strategyResults := range results {
+		for _, result := range strategyResults {
+			if isFinished(result) {
+				statuses[result.Seed] = append(statuses[result.Seed], result.Status)
+			}
+		}
+	}
+
+	seeds := make([]int64, 0)
+	for seed, seedStatuses := range statuses {
+		if len(seedStatuses) != len(results) {
+			continue
+		}
+		for _, status := range seedStatuses[1:] {
+			if status != seedStatuses[0] {
+				seeds = append(seeds, seed)
+				break
+			}
+		}
+	}
+	sort.Slice(seeds, func(i, j int) bool { return seeds[i] < seeds[j] })
+	return seeds
+}
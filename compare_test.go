@@ -0,0 +1,13 @@
+package main
+
+import "testing"
+
+func TestCountUnpaired(t *testing.T) {
+	results := [][]gameResult{
+		{{GameId: "a", Seed: 1, BoardSeed: 1}, {GameId: "b", Seed: 2, BoardSeed: 2}, {Seed: 3}},
+		{{GameId: "c", Seed: 1, BoardSeed: 1}, {GameId: "d", Seed: 2, BoardSeed: 0}, {GameId: "e", Seed: 3, BoardSeed: 7}},
+	}
+	if got := countUnpaired(results); got != 2 {
+		t.Errorf("countUnpaired = %d, want 2: a seed that's missing and one that's different", got)
+	}
+}
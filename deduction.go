@@ -0,0 +1,119 @@
+package main
+
+// deduceUntilStuck applies the single-cell rules, the constraint pair rules and, if asked to,
+// the global mine count over and over, queueing safe cells and marking bombs, until none of them
+// can find anything new.
+func (game *gameInformation) deduceUntilStuck(useMineCount bool) {
+	for {
+		game.refreshBombs()
+		game.findSafeCells()
+
+		queued := len(game.cellsToOpen)
+		safe, bombs := game.deduceFromConstraintPairs()
+		for _, loc := range safe {
+			game.queueCellToOpen(loc)
+		}
+		game.markBombs(bombs)
+
+		if len(bombs) > 0 || len(game.cellsToOpen) > queued {
+			continue
+		}
+		if !useMineCount {
+			return
+		}
+
+		// the cheap rules are exhausted, see if the mine count settles anything
+		safe, bombs = game.deduceFromMineCount()
+		for _, loc := range safe {
+			game.queueCellToOpen(loc)
+		}
+		game.markBombs(bombs)
+
+		if len(bombs) == 0 && len(game.cellsToOpen) == queued {
+			return
+		}
+	}
+}
+
+// deduceFromConstraintPairs compares the constraints of numbered cells that share unknown
+// neighbours. If A and B overlap and B needs exactly as many more mines than A as it has cells
+// outside of A, then those cells are all bombs and A's cells outside of B are all safe. And if A
+// is a subset of B with the same number of mines, B's other cells are safe. Together these
+// resolve the 1-2-1 and 1-2-2-1 patterns.
+func (game *gameInformation) deduceFromConstraintPairs() (safe []int, bombs []int) {
+	constraints := game.collectConstraints()
+
+	byCell := make(map[int][]int)
+	for i, c := range constraints {
+		for _, offset := range c.cells {
+			byCell[offset] = append(byCell[offset], i)
+		}
+	}
+
+	safeSet := newBitset(len(game.grid.cells))
+	bombSet := newBitset(len(game.grid.cells))
+	for i, a := range constraints {
+		compared := make(map[int]bool)
+		for _, offset := range a.cells {
+			for _, j := range byCell[offset] {
+				if j == i || compared[j] {
+					continue
+				}
+				compared[j] = true
+				b := constraints[j]
+
+				onlyA := subtractCells(a.cells, b.cells)
+				onlyB := subtractCells(b.cells, a.cells)
+				if len(onlyB) == 0 {
+					continue
+				}
+				if len(onlyA) == 0 && a.mines == b.mines { // A's mines are all B's mines
+					for _, offset := range onlyB {
+						safeSet.add(offset)
+					}
+				}
+				if b.mines-a.mines == len(onlyB) {
+					for _, offset := range onlyB {
+						bombSet.add(offset)
+					}
+					for _, offset := range onlyA {
+						safeSet.add(offset)
+					}
+				}
+			}
+		}
+	}
+
+	for _, offset := range safeSet.members() {
+		if !bombSet.has(offset) {
+			safe = append(safe, offset)
+		}
+	}
+	return safe, bombSet.members()
+}
+
+// markBombs records cells that are certain to contain a bomb and marks them on the board.
+func (game *gameInformation) markBombs(offsets []int) {
+	for _, offset := range offsets {
+		game.markBomb(offset)
+	}
+}
+
+// subtractCells returns the cells of `from` that aren't in `cells`. Both are a number's unknown
+// neighbours, so they're short enough for a linear scan.
+func subtractCells(from, cells []int) []int {
+	result := make([]int, 0)
+	for _, offset := range from {
+		found := false
+		for _, other := range cells {
+			if offset == other {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, offset)
+		}
+	}
+	return result
+}
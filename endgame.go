@@ -0,0 +1,54 @@
+package main
+
+// probabilities this close to 0 or 1 come from rounding, not from a real chance of being wrong
+const certaintyEpsilon = 1e-9
+
+// minesLeft is the number of bombs that haven't been located yet, or -1 when the server didn't
+// say how many there are.
+func (game *gameInformation) minesLeft() int {
+	if game.MinesCount <= 0 {
+		return -1
+	}
+	return int(game.MinesCount) - game.grid.bombs.count()
+}
+
+// deduceFromMineCount uses the number of mines left on the board to settle cells that the
+// numbers alone can't. When no mines are left every unknown cell is safe, when there are as many
+// mines as unknown cells they're all bombs, and otherwise the global count rules out some
+// frontier configurations, which can leave cells (interior ones included) that are a bomb in
+// none or in all of the remaining ones.
+func (game *gameInformation) deduceFromMineCount() (safe []int, bombs []int) {
+	if game.MinesCount <= 0 { // server didn't tell us, nothing to count against
+		return nil, nil
+	}
+
+	unknowns := make([]int, 0)
+	for offset, value := range game.grid.cells {
+		if value == gridUnknown && !game.queued.has(offset) {
+			unknowns = append(unknowns, offset)
+		}
+	}
+	if len(unknowns) == 0 {
+		return nil, nil
+	}
+
+	minesLeft := game.minesLeft()
+	if minesLeft == 0 {
+		return unknowns, nil
+	}
+	if minesLeft == len(unknowns) {
+		return nil, unknowns
+	}
+
+	for offset, risk := range game.mineProbabilities() {
+		if risk == noProbability {
+			continue
+		}
+		if risk <= certaintyEpsilon {
+			safe = append(safe, offset)
+		} else if risk >= 1-certaintyEpsilon {
+			bombs = append(bombs, offset)
+		}
+	}
+	return safe, bombs
+}
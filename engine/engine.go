@@ -0,0 +1,377 @@
+// Package engine is an in-process minesweeper game that follows the rules of
+// minesweeper-server and speaks its model (swagger.Game, swagger.MoveInfo), so the bot can play
+// without a server or a network.
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"minesweeper-bot/swagger"
+)
+
+const (
+	statusWin  = "win"
+	statusLose = "lose"
+)
+
+// ErrUnknownGame is returned for moves in games this engine hasn't created, or that are over:
+// the engine forgets a game as soon as it's won or lost.
+var ErrUnknownGame = errors.New("unknown game id")
+
+// IllegalMoveError is returned for moves the game doesn't accept: cells outside of the board,
+// unknown actions, flagging an open cell and chording a hidden one.
+type IllegalMoveError struct {
+	GameId string
+	X, Y   int
+	Reason string
+}
+
+func (e IllegalMoveError) Error() string {
+	return fmt.Sprintf("illegal move (%d, %d) in game %s: %s", e.X, e.Y, e.GameId, e.Reason)
+}
+
+// Config describes the boards an engine creates.
+type Config struct {
+	Width  int
+	Height int
+	Mines  int
+	// FirstClick is where the mines may go relative to the first cell opened. Empty means
+	// FirstClickCentre.
+	FirstClick FirstClick
+}
+
+// FirstClick is what a game guarantees about the first cell opened in it.
+type FirstClick string
+
+const (
+	// FirstClickCentre never puts a mine into the centre cell, like minesweeper-server, so that
+	// opening it first is safe. Any other first click may hit a mine.
+	FirstClickCentre FirstClick = "centre"
+	// FirstClickSafe places the mines when the first cell is opened, never into that cell.
+	FirstClickSafe FirstClick = "safe"
+	// FirstClickZero places the mines when the first cell is opened, into neither that cell nor
+	// its neighbours, so the first click always opens an area. On boards too crowded for that, it
+	// only keeps the cell itself free.
+	FirstClickZero FirstClick = "zero"
+	// FirstClickNone puts mines anywhere, so even the first click can lose.
+	FirstClickNone FirstClick = "none"
+)
+
+// FirstClickNames lists the known FirstClick rules.
+func FirstClickNames() []string {
+	return []string{string(FirstClickCentre), string(FirstClickSafe), string(FirstClickZero), string(FirstClickNone)}
+}
+
+// DefaultConfig is the board minesweeper-server hands out: 16x16 with 40 mines.
+func DefaultConfig() Config {
+	return Config{Width: 16, Height: 16, Mines: 40}
+}
+
+// difficulties are the classic boards, by the names minesweeper-server knows them by.
+var difficulties = map[string]Config{
+	"beginner":     {Width: 9, Height: 9, Mines: 10},
+	"intermediate": {Width: 16, Height: 16, Mines: 40},
+	"expert":       {Width: 30, Height: 16, Mines: 99},
+}
+
+// Difficulty returns the board of a named difficulty.
+func Difficulty(name string) (Config, error) {
+	cfg, ok := difficulties[name]
+	if !ok {
+		return Config{}, fmt.Errorf("unknown difficulty %q, expected one of %v", name, DifficultyNames())
+	}
+	return cfg, nil
+}
+
+// DifficultyNames lists the known difficulties from the smallest board to the biggest.
+func DifficultyNames() []string {
+	return []string{"beginner", "intermediate", "expert"}
+}
+
+// Engine keeps any number of games in memory, until they're over. It's safe for concurrent use.
+type Engine struct {
+	cfg Config
+
+	mu    sync.Mutex
+	rand  *rand.Rand
+	games map[string]*board
+}
+
+func New(cfg Config) *Engine {
+	return &Engine{
+		cfg:   cfg,
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		games: make(map[string]*board),
+	}
+}
+
+// NewGame creates a board of the engine's config and returns its initial state with every cell
+// hidden. Mines are placed according to the config's FirstClick rule, using seed alone, so the
+// same seed and config always give the same board; with FirstClickSafe and FirstClickZero, as long
+// as the first cell opened is the same too.
+func (e *Engine) NewGame(seed int64) (swagger.Game, error) {
+	return e.NewCustomGame(seed, e.cfg)
+}
+
+// NewCustomGame is NewGame with a board of its own size and number of mines.
+func (e *Engine) NewCustomGame(seed int64, cfg Config) (swagger.Game, error) {
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return swagger.Game{}, fmt.Errorf("invalid board size %dx%d", cfg.Width, cfg.Height)
+	}
+	if cfg.Mines < 0 || cfg.Mines >= cfg.Width*cfg.Height {
+		return swagger.Game{}, fmt.Errorf("can't place %d mines on a %dx%d board", cfg.Mines, cfg.Width, cfg.Height)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	b := newBoard(e.newGameId(), cfg.Width, cfg.Height)
+	b.seed = seed
+	b.minesCount = cfg.Mines
+	b.hiddenSafe = len(b.mines) - cfg.Mines
+	switch cfg.FirstClick {
+	case "", FirstClickCentre:
+		b.placeMines([]int{b.offset(cfg.Width/2, cfg.Height/2)})
+	case FirstClickNone:
+		b.placeMines(nil)
+	case FirstClickSafe, FirstClickZero:
+		b.firstClick = cfg.FirstClick
+	default:
+		return swagger.Game{}, fmt.Errorf("unknown first click rule %q, expected one of %v", cfg.FirstClick, FirstClickNames())
+	}
+	e.games[b.id] = b
+	return b.state(), nil
+}
+
+// Move plays moveInfo.Action on a cell and returns the new state of the game.
+//
+// Opening a cell with no mines around it opens its neighbours too, and so on. Opening a cell
+// that's already open or flagged changes nothing. Flags only go on hidden cells, and unflagging
+// a cell without a flag changes nothing. Chording an open cell opens all its hidden neighbours
+// without a flag, if it has as many flags around as its number, and changes nothing otherwise.
+// A wrong flag makes the chord open a mine.
+func (e *Engine) Move(moveInfo swagger.MoveInfo) (swagger.Game, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	b, ok := e.games[moveInfo.GameId]
+	if !ok {
+		return swagger.Game{}, ErrUnknownGame
+	}
+	x, y := int(moveInfo.X), int(moveInfo.Y)
+	if x < 0 || y < 0 || x >= b.width || y >= b.height {
+		return swagger.Game{}, IllegalMoveError{GameId: b.id, X: x, Y: y, Reason: "outside of the board"}
+	}
+
+	offset := b.offset(x, y)
+	switch moveInfo.Action {
+	case "", swagger.MoveActionOpen:
+		if !b.placed && !b.flagged[offset] {
+			b.placeMinesAround(offset)
+		}
+		b.open(offset)
+	case swagger.MoveActionFlag:
+		if b.revealed[offset] {
+			return swagger.Game{}, IllegalMoveError{GameId: b.id, X: x, Y: y, Reason: "can't flag an open cell"}
+		}
+		b.flagged[offset] = true
+	case swagger.MoveActionUnflag:
+		b.flagged[offset] = false
+	case swagger.MoveActionChord:
+		if !b.revealed[offset] {
+			return swagger.Game{}, IllegalMoveError{GameId: b.id, X: x, Y: y, Reason: "can't chord a hidden cell"}
+		}
+		b.chord(offset)
+	default:
+		return swagger.Game{}, IllegalMoveError{GameId: b.id, X: x, Y: y, Reason: fmt.Sprintf("unknown action %q", moveInfo.Action)}
+	}
+	if b.status != "" {
+		delete(e.games, b.id)
+	}
+	return b.state(), nil
+}
+
+func (e *Engine) newGameId() string {
+	return fmt.Sprintf("%08x-%04x-4%03x-%04x-%012x",
+		e.rand.Uint32(), e.rand.Intn(1<<16), e.rand.Intn(1<<12), 0x8000|e.rand.Intn(1<<14), e.rand.Int63n(1<<48))
+}
+
+type board struct {
+	id            string
+	seed          int64
+	width, height int
+	mines         []bool
+	revealed      []bool
+	flagged       []bool
+	minesCount    int
+	hiddenSafe    int // safe cells not opened yet, the game is won when it gets to 0
+	status        string
+	// placed is false until the mines are placed; with firstClick FirstClickSafe or
+	// FirstClickZero, that's when the first cell is opened
+	placed     bool
+	firstClick FirstClick
+}
+
+func newBoard(id string, width, height int) *board {
+	return &board{
+		id:       id,
+		width:    width,
+		height:   height,
+		mines:    make([]bool, width*height),
+		revealed: make([]bool, width*height),
+		flagged:  make([]bool, width*height),
+	}
+}
+
+func (b *board) offset(x, y int) int {
+	return y*b.width + x
+}
+
+// placeMines scatters the board's mines uniformly over every cell but the safe ones, using the
+// board's seed.
+func (b *board) placeMines(safe []int) {
+	excluded := make(map[int]bool, len(safe))
+	for _, offset := range safe {
+		excluded[offset] = true
+	}
+	candidates := make([]int, 0, len(b.mines)-len(safe))
+	for offset := range b.mines {
+		if !excluded[offset] {
+			candidates = append(candidates, offset)
+		}
+	}
+	r := rand.New(rand.NewSource(b.seed))
+	r.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	for _, offset := range candidates[:b.minesCount] {
+		b.mines[offset] = true
+	}
+	b.placed = true
+}
+
+// placeMinesAround places the mines of a board whose first cell is being opened, following its
+// firstClick rule.
+func (b *board) placeMinesAround(offset int) {
+	safe := []int{offset}
+	if b.firstClick == FirstClickZero && len(b.mines)-1-len(b.neighbours(offset)) >= b.minesCount {
+		safe = append(safe, b.neighbours(offset)...)
+	}
+	b.placeMines(safe)
+}
+
+func (b *board) neighbours(offset int) []int {
+	y := offset / b.width
+	x := offset - y*b.width
+	result := make([]int, 0, 8)
+	for j := y - 1; j <= y+1; j++ {
+		for i := x - 1; i <= x+1; i++ {
+			if i == x && j == y || i < 0 || j < 0 || i >= b.width || j >= b.height {
+				continue
+			}
+			result = append(result, b.offset(i, j))
+		}
+	}
+	return result
+}
+
+func (b *board) minesAround(offset int) int {
+	count := 0
+	for _, n := range b.neighbours(offset) {
+		if b.mines[n] {
+			count++
+		}
+	}
+	return count
+}
+
+func (b *board) open(offset int) {
+	if b.revealed[offset] || b.flagged[offset] {
+		return
+	}
+	if b.mines[offset] {
+		b.status = statusLose
+		return
+	}
+
+	// flood fill through cells with no mines around
+	pending := []int{offset}
+	b.revealed[offset] = true
+	for len(pending) > 0 {
+		current := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		b.hiddenSafe--
+		if b.minesAround(current) > 0 {
+			continue
+		}
+		for _, n := range b.neighbours(current) {
+			// flagged cells stay closed, as in any minesweeper, even though the flag must be wrong
+			if !b.revealed[n] && !b.flagged[n] {
+				b.revealed[n] = true
+				pending = append(pending, n)
+			}
+		}
+	}
+
+	if b.hiddenSafe == 0 {
+		b.status = statusWin
+	}
+}
+
+func (b *board) chord(offset int) {
+	flags := 0
+	for _, n := range b.neighbours(offset) {
+		if b.flagged[n] {
+			flags++
+		}
+	}
+	if flags != b.minesAround(offset) {
+		return
+	}
+	for _, n := range b.neighbours(offset) {
+		if b.status != "" {
+			return
+		}
+		b.open(n)
+	}
+}
+
+// state renders the board the way the server does: "?" for hidden cells, "F" for flagged ones,
+// the number of neighbouring mines for open ones, and "*" for every mine once the game is over.
+func (b *board) state() swagger.Game {
+	cells := make([]string, len(b.mines))
+	for offset := range cells {
+		switch {
+		case b.mines[offset] && b.status != "":
+			cells[offset] = "*"
+		case b.revealed[offset]:
+			cells[offset] = strconv.Itoa(b.minesAround(offset))
+		case b.flagged[offset]:
+			cells[offset] = "F"
+		default:
+			cells[offset] = "?"
+		}
+	}
+
+	rows := make([]string, b.height)
+	for y := range rows {
+		rows[y] = strings.Join(cells[y*b.width:(y+1)*b.width], " ")
+	}
+
+	return swagger.Game{
+		GameId:           b.id,
+		Status:           b.status,
+		BoardWidth:       int32(b.width),
+		BoardHeight:      int32(b.height),
+		MinesCount:       int32(b.minesCount),
+		BoardState:       cells,
+		PrettyBoardState: strings.Join(rows, "\n"),
+		Seed:             b.seed,
+	}
+}
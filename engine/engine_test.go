@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"minesweeper-bot/swagger"
+)
+
+// newTestGame adds a game with the mines where layout has "*", one row per string, and returns
+// its id.
+func newTestGame(e *Engine, layout ...string) string {
+	b := newBoard(e.newGameId(), len(layout[0]), len(layout))
+	for y, row := range layout {
+		for x, cell := range row {
+			if cell == '*' {
+				b.mines[b.offset(x, y)] = true
+				b.minesCount++
+			}
+		}
+	}
+	b.hiddenSafe = len(b.mines) - b.minesCount
+	b.placed = true
+	e.games[b.id] = b
+	return b.id
+}
+
+func move(t *testing.T, e *Engine, id string, x, y int, action string) swagger.Game {
+	t.Helper()
+	game, err := e.Move(swagger.MoveInfo{GameId: id, X: int32(x), Y: int32(y), Action: action})
+	if err != nil {
+		t.Fatalf("move (%d, %d) %s: %v", x, y, action, err)
+	}
+	return game
+}
+
+func rows(game swagger.Game) string {
+	result := make([]string, 0, game.BoardHeight)
+	for y := 0; y < int(game.BoardHeight); y++ {
+		result = append(result, strings.Join(game.BoardState[y*int(game.BoardWidth):(y+1)*int(game.BoardWidth)], ""))
+	}
+	return strings.Join(result, "/")
+}
+
+var testLayout = []string{
+	"....",
+	"....",
+	"...*",
+	"..*.",
+}
+
+func TestMoveFloodFill(t *testing.T) {
+	e := New(DefaultConfig())
+	id := newTestGame(e, testLayout...)
+
+	game := move(t, e, id, 0, 0, swagger.MoveActionOpen)
+	if want := "0000/0011/012?/01??"; rows(game) != want || game.Status != "" {
+		t.Fatalf("after opening a zero got %s %q, want %s", rows(game), game.Status, want)
+	}
+	game = move(t, e, id, 2, 1, swagger.MoveActionOpen)
+	if want := "0000/0011/012?/01??"; rows(game) != want {
+		t.Errorf("opening an open cell changed the board to %s", rows(game))
+	}
+}
+
+func TestMoveFloodFillStopsAtFlags(t *testing.T) {
+	e := New(DefaultConfig())
+	id := newTestGame(e, testLayout...)
+
+	move(t, e, id, 0, 3, swagger.MoveActionFlag)
+	game := move(t, e, id, 0, 0, swagger.MoveActionOpen)
+	if want := "0000/0011/012?/F1??"; rows(game) != want {
+		t.Errorf("got %s, want %s", rows(game), want)
+	}
+}
+
+func TestMoveWin(t *testing.T) {
+	e := New(DefaultConfig())
+	id := newTestGame(e, testLayout...)
+
+	move(t, e, id, 0, 0, swagger.MoveActionOpen)
+	game := move(t, e, id, 3, 3, swagger.MoveActionOpen)
+	if game.Status != statusWin {
+		t.Fatalf("status is %q after opening every safe cell, want %q", game.Status, statusWin)
+	}
+	if want := "0000/0011/012*/01*2"; rows(game) != want {
+		t.Errorf("got %s, want %s with the mines shown", rows(game), want)
+	}
+	if _, err := e.Move(swagger.MoveInfo{GameId: id, X: 0, Y: 0}); err != ErrUnknownGame {
+		t.Errorf("got %v for a move after the game is over, want ErrUnknownGame", err)
+	}
+	if len(e.games) != 0 {
+		t.Errorf("%d games still kept after the game is over", len(e.games))
+	}
+}
+
+func TestMoveLose(t *testing.T) {
+	e := New(DefaultConfig())
+	id := newTestGame(e, testLayout...)
+
+	game := move(t, e, id, 3, 2, swagger.MoveActionOpen)
+	if game.Status != statusLose {
+		t.Fatalf("status is %q after opening a mine, want %q", game.Status, statusLose)
+	}
+	if want := "????/????/???*/??*?"; rows(game) != want {
+		t.Errorf("got %s, want %s with the mines shown", rows(game), want)
+	}
+	if len(e.games) != 0 {
+		t.Errorf("%d games still kept after the game is over", len(e.games))
+	}
+}
+
+func TestMoveChord(t *testing.T) {
+	e := New(DefaultConfig())
+	id := newTestGame(e, testLayout...)
+
+	move(t, e, id, 0, 0, swagger.MoveActionOpen)
+	move(t, e, id, 3, 2, swagger.MoveActionFlag)
+	move(t, e, id, 2, 3, swagger.MoveActionFlag)
+	game := move(t, e, id, 2, 2, swagger.MoveActionChord)
+	if game.Status != statusWin {
+		t.Errorf("status is %q after chording the last safe cell open, want %q", game.Status, statusWin)
+	}
+}
+
+func TestMoveOutsideOfTheBoard(t *testing.T) {
+	e := New(DefaultConfig())
+	id := newTestGame(e, testLayout...)
+
+	_, err := e.Move(swagger.MoveInfo{GameId: id, X: 4, Y: 0})
+	if _, ok := err.(IllegalMoveError); !ok {
+		t.Errorf("got %v for a move outside of the board, want an IllegalMoveError", err)
+	}
+	if _, err := e.Move(swagger.MoveInfo{GameId: "nope"}); err != ErrUnknownGame {
+		t.Errorf("got %v for an unknown game, want ErrUnknownGame", err)
+	}
+}
+
+// seededMines creates a game from seed on a fresh engine, opens (3, 3) and returns where its
+// mines are, with "*" for a mine and "." for a safe cell.
+func seededMines(t *testing.T, seed int64, cfg Config) string {
+	t.Helper()
+	e := New(cfg)
+	game, err := e.NewGame(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the engine forgets the game once the first click ends it
+	b := e.games[game.GameId]
+	move(t, e, game.GameId, 3, 3, swagger.MoveActionOpen)
+	var layout strings.Builder
+	for _, mine := range b.mines {
+		if mine {
+			layout.WriteByte('*')
+		} else {
+			layout.WriteByte('.')
+		}
+	}
+	return layout.String()
+}
+
+func TestNewGameIsSeeded(t *testing.T) {
+	for _, firstClick := range FirstClickNames() {
+		cfg := Config{Width: 9, Height: 9, Mines: 10, FirstClick: FirstClick(firstClick)}
+		board := seededMines(t, 42, cfg)
+		if strings.Count(board, "*") != cfg.Mines {
+			t.Errorf("%s: %d mines, want %d", firstClick, strings.Count(board, "*"), cfg.Mines)
+		}
+		if again := seededMines(t, 42, cfg); again != board {
+			t.Errorf("%s: the same seed gave two boards:\n%s\n%s", firstClick, board, again)
+		}
+		if other := seededMines(t, 43, cfg); other == board {
+			t.Errorf("%s: seeds 42 and 43 gave the same board %s", firstClick, board)
+		}
+	}
+}
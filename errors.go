@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"minesweeper-bot/engine"
+	"minesweeper-bot/swagger"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// gameErrorKind says what went wrong while talking to a backend.
+type gameErrorKind string
+
+const (
+	// errorTransient is a network failure or an overloaded server; trying again later may work.
+	errorTransient gameErrorKind = "transient"
+	// errorUnknownGame means the backend doesn't know the game id, e.g. because it restarted.
+	errorUnknownGame gameErrorKind = "unknown_game"
+	// errorIllegalMove means the backend refused the move.
+	errorIllegalMove gameErrorKind = "illegal_move"
+	// errorServer is anything else going wrong on the backend's side.
+	errorServer gameErrorKind = "server"
+)
+
+// gameError is an error from a backend call, classified by kind.
+type gameError struct {
+	Kind gameErrorKind
+	Op   string // the backend call: "newgame" or "move"
+	Err  error
+}
+
+func (e gameError) Error() string {
+	return fmt.Sprintf("%s: %s error: %v", e.Op, e.Kind, e.Err)
+}
+
+// classifySwaggerError turns an error from the generated client into a gameError, based on
+// the HTTP status of the response, if there was one.
+func classifySwaggerError(op string, err error, response *http.Response) error {
+	if err == nil {
+		return nil
+	}
+
+	kind := errorServer
+	if _, ok := err.(swagger.GenericSwaggerError); ok && response != nil {
+		kind = kindOfHTTPStatus(response.StatusCode)
+	} else if isNetworkError(err) {
+		kind = errorTransient
+	}
+	return gameError{Kind: kind, Op: op, Err: err}
+}
+
+func kindOfHTTPStatus(status int) gameErrorKind {
+	switch {
+	case status == http.StatusNotFound:
+		return errorUnknownGame
+	case status == http.StatusRequestTimeout, status == http.StatusTooManyRequests,
+		status == http.StatusBadGateway, status == http.StatusServiceUnavailable, status == http.StatusGatewayTimeout:
+		return errorTransient
+	case status >= 400 && status < 500:
+		return errorIllegalMove
+	default:
+		return errorServer
+	}
+}
+
+func isNetworkError(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	_, ok := err.(net.Error)
+	return ok
+}
+
+// classifyEngineError turns an error from the local engine into a gameError.
+func classifyEngineError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	kind := errorServer
+	if err == engine.ErrUnknownGame {
+		kind = errorUnknownGame
+	} else if _, ok := err.(engine.IllegalMoveError); ok {
+		kind = errorIllegalMove
+	}
+	return gameError{Kind: kind, Op: op, Err: err}
+}
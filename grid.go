@@ -0,0 +1,178 @@
+package main
+
+import (
+	"math/bits"
+	"minesweeper-bot/swagger"
+	"sync"
+)
+
+// Values of grid cells other than the numbers 0 to 8.
+const (
+	gridUnknown int8 = -1
+	gridBomb    int8 = -2
+)
+
+// grid is the solver's view of a board. Every cell is a byte: the number of an open cell,
+// gridUnknown or gridBomb. The unknown cells and the bombs are kept as bitsets too, and the
+// neighbours of every cell come from a table shared by all boards of the same size, so the
+// solver's passes over the board neither parse strings nor allocate.
+type grid struct {
+	width, height int
+	cells         []int8
+	unknown       bitset
+	bombs         bitset
+	// neighbours[offset] are the offsets of the cells around offset
+	neighbours [][]int
+}
+
+// newGrid converts a board received from the server. Flagged cells are bombs.
+func newGrid(game *swagger.Game) grid {
+	width, height := int(game.BoardWidth), int(game.BoardHeight)
+	g := grid{
+		width:      width,
+		height:     height,
+		cells:      make([]int8, len(game.BoardState)),
+		unknown:    newBitset(len(game.BoardState)),
+		bombs:      newBitset(len(game.BoardState)),
+		neighbours: neighbourTable(width, height),
+	}
+	for offset, cellState := range game.BoardState {
+		switch {
+		case cellState == "?":
+			g.cells[offset] = gridUnknown
+			g.unknown.add(offset)
+		case cellState == "*" || cellState == flaggedCell:
+			g.cells[offset] = gridBomb
+			g.bombs.add(offset)
+		case len(cellState) == 1 && cellState[0] >= '0' && cellState[0] <= '8':
+			g.cells[offset] = int8(cellState[0] - '0')
+		default:
+			// not something the server sends, treat it like a cell we know nothing about
+			g.cells[offset] = gridUnknown
+			g.unknown.add(offset)
+		}
+	}
+	return g
+}
+
+func (g *grid) offset(loc location) int {
+	return loc.Y*g.width + loc.X
+}
+
+func (g *grid) location(offset int) location {
+	return location{offset % g.width, offset / g.width}
+}
+
+func (g *grid) contains(loc location) bool {
+	return loc.X >= 0 && loc.Y >= 0 && loc.X < g.width && loc.Y < g.height
+}
+
+// number returns the number of an open cell, and false for unknown cells and bombs.
+func (g *grid) number(offset int) (int, bool) {
+	value := g.cells[offset]
+	return int(value), value >= 0
+}
+
+func (g *grid) markBomb(offset int) {
+	g.cells[offset] = gridBomb
+	g.unknown.remove(offset)
+	g.bombs.add(offset)
+}
+
+// countAround is the number of cells around offset holding value.
+func (g *grid) countAround(offset int, value int8) int {
+	count := 0
+	for _, n := range g.neighbours[offset] {
+		if g.cells[n] == value {
+			count++
+		}
+	}
+	return count
+}
+
+// appendAround appends the cells around offset holding value to buf and returns it, so that
+// callers can reuse one buffer for every query.
+func (g *grid) appendAround(buf []int, offset int, value int8) []int {
+	for _, n := range g.neighbours[offset] {
+		if g.cells[n] == value {
+			buf = append(buf, n)
+		}
+	}
+	return buf
+}
+
+// neighbourTables caches a neighbour table per board size, keyed by [2]int{width, height}.
+var neighbourTables sync.Map
+
+// neighbourTable lists the neighbours of every cell of a width x height board. They're in the
+// order the solver has always visited them, column by column, which keeps its sums (and so
+// its choices between equally risky cells) exactly as they were.
+func neighbourTable(width, height int) [][]int {
+	key := [2]int{width, height}
+	if table, ok := neighbourTables.Load(key); ok {
+		return table.([][]int)
+	}
+	table := make([][]int, width*height)
+	all := make([]int, 0, width*height*8)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			start := len(all)
+			for i := x - 1; i <= x+1; i++ {
+				for j := y - 1; j <= y+1; j++ {
+					if i == x && j == y || i < 0 || j < 0 || i >= width || j >= height {
+						continue
+					}
+					all = append(all, j*width+i)
+				}
+			}
+			table[y*width+x] = all[start:len(all):len(all)]
+		}
+	}
+	actual, _ := neighbourTables.LoadOrStore(key, table)
+	return actual.([][]int)
+}
+
+// bitset is a set of cells, by offset.
+type bitset []uint64
+
+func newBitset(size int) bitset {
+	return make(bitset, (size+63)/64)
+}
+
+func (b bitset) has(i int) bool {
+	return b[i/64]&(1<<uint(i%64)) != 0
+}
+
+func (b bitset) add(i int) {
+	b[i/64] |= 1 << uint(i%64)
+}
+
+func (b bitset) remove(i int) {
+	b[i/64] &^= 1 << uint(i%64)
+}
+
+func (b bitset) clear() {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+func (b bitset) count() int {
+	count := 0
+	for _, word := range b {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+// members returns the cells in the set in ascending order, which is the order of location.less.
+func (b bitset) members() []int {
+	result := make([]int, 0, b.count())
+	for w, word := range b {
+		for word != 0 {
+			result = append(result, w*64+bits.TrailingZeros64(word))
+			word &= word - 1
+		}
+	}
+	return result
+}
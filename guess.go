@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	// guessRiskTolerance is how much riskier than the least risky cell a guess may be when it's
+	// more likely to get the bot going again
+	guessRiskTolerance = 0.05
+	// maxGuessCandidates caps the cells findBestGuess looks into, since every one of them takes a
+	// run of the probability solver per number it could show
+	maxGuessCandidates = 20
+	// maxProgressCells is the size of frontier around a cell above which progressChance doesn't
+	// try: enumerating it again for every number would take longer than the game
+	maxProgressCells = 16
+)
+
+// findBestGuess picks the cell to guess when nothing is certain. The least risky cell isn't
+// always the best guess: one that's a little riskier but likely to show a zero, or a number that
+// settles the cells around it, saves the guesses that would follow. So every cell within
+// guessRiskTolerance of the least risky one is scored by its chance of being safe times one
+// plus its chance of making progress, see progressChance, and the best score wins. Candidates are
+// tried by risk, then corners and edges before the middle of the board, then in the order of
+// location.less, and a later one has to score strictly better, so the same board always gets the
+// same guess. It returns the cell along with its chance of holding a bomb.
+func (game *gameInformation) findBestGuess() (location, float64, error) {
+	g := &game.grid
+	components := enumerateComponents(game.collectConstraints(), len(g.cells))
+	probabilities, _ := game.componentProbabilities(components, game.queued)
+	minRisk := math.Inf(1)
+	candidates := make([]int, 0)
+	for offset, risk := range probabilities {
+		if risk == noProbability {
+			continue
+		}
+		candidates = append(candidates, offset)
+		minRisk = math.Min(minRisk, risk)
+	}
+	if len(candidates) == 0 {
+		return location{}, 0, fmt.Errorf("can't find a cell to guess")
+	}
+
+	// risks that differ only by rounding count as equal
+	riskKey := func(offset int) int64 {
+		return int64(math.Round(probabilities[offset] / certaintyEpsilon))
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if riskKey(a) != riskKey(b) {
+			return riskKey(a) < riskKey(b)
+		}
+		if len(g.neighbours[a]) != len(g.neighbours[b]) {
+			return len(g.neighbours[a]) < len(g.neighbours[b])
+		}
+		return a < b
+	})
+	kept := 0
+	for kept < len(candidates) && kept < maxGuessCandidates && probabilities[candidates[kept]] <= minRisk+guessRiskTolerance {
+		kept++
+	}
+
+	best, bestScore := -1, 0.0
+	for _, offset := range candidates[:kept] {
+		score := (1 - probabilities[offset]) * (1 + game.progressChance(offset, components))
+		if best < 0 || score > bestScore+certaintyEpsilon {
+			best, bestScore = offset, score
+		}
+	}
+	return g.location(best), probabilities[best], nil
+}
+
+// progressChance is the chance that opening a cell, if it's safe, lets the solver settle some
+// other cell: for every number the cell could show, the probability solver runs again with that
+// number as one more constraint, and the chances of the numbers that leave a cell certain to be
+// safe or a bomb are added up. A zero is one of them, as are numbers that resolve the constraints
+// of the numbers around. components are the board's, enumerated; only the ones the cell or its
+// neighbours are part of get enumerated again, and if there are more than maxProgressCells cells
+// in them, the chance is taken to be 0.
+func (game *gameInformation) progressChance(offset int, components []*frontierComponent) float64 {
+	g := &game.grid
+	safe := newBitset(len(g.cells))
+	copy(safe, game.queued)
+	safe.add(offset)
+	around := make([]int, 0, 8)
+	touched := newBitset(len(g.cells))
+	touched.add(offset)
+	for _, n := range g.neighbours[offset] {
+		if g.cells[n] == gridUnknown && !safe.has(n) {
+			around = append(around, n)
+			touched.add(n)
+		}
+	}
+
+	unaffected := make([]*frontierComponent, 0, len(components))
+	affectedCells := 0
+	// the cell is open whatever it shows, so it's no longer part of the constraints around it
+	base := make([]constraint, 0)
+	for _, component := range components {
+		affected := false
+		for _, cell := range component.cells {
+			if touched.has(cell) {
+				affected = true
+				break
+			}
+		}
+		if !affected {
+			unaffected = append(unaffected, component)
+			continue
+		}
+		affectedCells += len(component.cells)
+		for _, c := range component.offsetConstraints() {
+			cells := c.cells[:0]
+			for _, cell := range c.cells {
+				if cell != offset {
+					cells = append(cells, cell)
+				}
+			}
+			if len(cells) > 0 {
+				base = append(base, constraint{cells: cells, mines: c.mines})
+			}
+		}
+	}
+	if affectedCells > maxProgressCells {
+		return 0
+	}
+
+	// logWays[i] is the log of the number of boards on which the cell shows the i-th possible
+	// number, settles[i] whether that number settles any cell
+	logWays := make([]float64, 0, len(around)+1)
+	settles := make([]bool, 0, len(around)+1)
+	for mines := 0; mines <= len(around); mines++ {
+		outcome := base
+		if len(around) > 0 {
+			outcome = append(base[:len(base):len(base)], constraint{cells: around, mines: mines})
+		}
+		all := append(unaffected[:len(unaffected):len(unaffected)], enumerateComponents(outcome, len(g.cells))...)
+		probabilities, ways := game.componentProbabilities(all, safe)
+		if math.IsInf(ways, -1) { // the cell can't show this number
+			continue
+		}
+		settled := false
+		for _, risk := range probabilities {
+			if risk != noProbability && (risk <= certaintyEpsilon || risk >= 1-certaintyEpsilon) {
+				settled = true
+				break
+			}
+		}
+		logWays = append(logWays, ways)
+		settles = append(settles, settled)
+	}
+
+	maxLogWays := math.Inf(-1)
+	for _, ways := range logWays {
+		maxLogWays = math.Max(maxLogWays, ways)
+	}
+	total, progress := 0.0, 0.0
+	for i, ways := range logWays {
+		weight := math.Exp(ways - maxLogWays)
+		total += weight
+		if settles[i] {
+			progress += weight
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return progress / total
+}
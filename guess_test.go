@@ -0,0 +1,106 @@
+package main
+
+import "testing"
+
+// stuckGame is the board after every deduction, which has to leave nothing certain to open.
+func stuckGame(t *testing.T, minesCount int32, board string) gameInformation {
+	t.Helper()
+	game := newGameInfo(testBoard(minesCount, board))
+	game.addFullyRevealedLocations()
+	game.deduceUntilStuck(minesCount > 0)
+	if len(game.cellsToOpen) > 0 {
+		t.Fatalf("%s has safe cells: %v", board, game.cellsToOpen)
+	}
+	return game
+}
+
+func TestFindBestGuess(t *testing.T) {
+	tests := []struct {
+		name       string
+		minesCount int32
+		board      string
+		want       location
+		// riskier is whether the guess is riskier than the least risky cell
+		riskier bool
+	}{
+		{
+			// the corner is a little likelier to hold a mine than (1, 3), but likelier to settle
+			// the cells around it too
+			name:       "progress beats a little less risk",
+			minesCount: 5,
+			board:      "????/??2?/??21/????/?1??",
+			want:       location{0, 4},
+			riskier:    true,
+		},
+		{
+			// (1, 0) is as risky and as likely to make progress, but the corner comes first
+			name:       "corners win ties",
+			minesCount: 4,
+			board:      "1???/???1/2???/????",
+			want:       location{3, 0},
+		},
+		{
+			// and so does this one over (1, 0), though it's further down the board
+			name:       "corners win ties at the bottom too",
+			minesCount: 6,
+			board:      "2??2/????/3?4?/????",
+			want:       location{0, 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := stuckGame(t, tt.minesCount, tt.board)
+			guess, risk, err := game.findBestGuess()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if guess != tt.want {
+				t.Errorf("guessed %v, want %v", guess, tt.want)
+			}
+			_, leastRisk, err := game.findLeastRiskyCell()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if riskier := risk > leastRisk+certaintyEpsilon; riskier != tt.riskier {
+				t.Errorf("guess risk is %v, least risk %v", risk, leastRisk)
+			}
+		})
+	}
+}
+
+func TestProgressChance(t *testing.T) {
+	game := stuckGame(t, 5, "????/??2?/??21/????/?1??")
+	components := enumerateComponents(game.collectConstraints(), len(game.grid.cells))
+	corner := game.progressChance(game.grid.offset(location{0, 4}), components)
+	leastRisky := game.progressChance(game.grid.offset(location{1, 3}), components)
+	if corner <= leastRisky {
+		t.Errorf("chance of progress is %v in the corner and %v in the least risky cell, want the corner's higher", corner, leastRisky)
+	}
+	if corner < 0 || corner > 1 || leastRisky < 0 || leastRisky > 1 {
+		t.Errorf("chances of progress %v and %v aren't probabilities", corner, leastRisky)
+	}
+}
+
+func TestFindBestGuessIsDeterministic(t *testing.T) {
+	boards := []struct {
+		minesCount int32
+		board      string
+	}{
+		{5, "????/??2?/??21/????/?1??"},
+		{4, "1???/???1/2???/????"},
+		{5, "????/????/????/????"},
+	}
+	for _, b := range boards {
+		game := stuckGame(t, b.minesCount, b.board)
+		first, _, err := game.findBestGuess()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 10; i++ {
+			game := stuckGame(t, b.minesCount, b.board)
+			if guess, _, _ := game.findBestGuess(); guess != first {
+				t.Errorf("%s: guessed %v, then %v", b.board, first, guess)
+			}
+		}
+	}
+}
@@ -4,32 +4,39 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"minesweeper-bot/engine"
 	"minesweeper-bot/swagger"
+	"os"
 	"sort"
 	"strings"
+	"time"
 )
 
 func main() {
-	configuration := swagger.NewConfiguration()
-	configuration.BasePath = "http://localhost:3000"
-	client := swagger.NewAPIClient(configuration)
-
-	gamesToPlay := 1000
-	results := make(map[string]int)
-	progress := make(map[int]int)
-	for i := 0; i < gamesToPlay; i++ {
-		thisGameResult := playNewGame(client)
-		results[thisGameResult.Status]++
-
-		progress[thisGameResult.MinesFound]++
+	os.Exit(runCommand(os.Args[1:]))
+}
 
-		fmt.Println(results)
+func playGames(ctx context.Context, gamesRunner runner, gamesToPlay int, seed int64, report reporter) error {
+	var reportErr error
+	stats := gamesRunner.run(ctx, gamesToPlay, seed, func(thisGameResult gameResult, stats *runStats) {
+		if err := report.gameFinished(thisGameResult, stats); err != nil && reportErr == nil {
+			reportErr = err
+		}
+	})
+	if reportErr != nil {
+		return reportErr
+	}
+	if err := report.runFinished(stats); err != nil {
+		return err
 	}
-	printProgressStats(progress)
+	if ctx.Err() != nil {
+		return fmt.Errorf("interrupted after %d of %d games", stats.finishedGames(), gamesToPlay)
+	}
+	return nil
 }
 
-func printProgressStats(gamesByMinesFound map[int]int) {
-	fmt.Println("progress in lost games")
+func printProgressStats(w io.Writer, gamesByMinesFound map[int]int) {
+	_, _ = fmt.Fprintln(w, "progress in lost games")
 	minesFoundKeys := make([]int, 0, len(gamesByMinesFound))
 	for key := range gamesByMinesFound {
 		minesFoundKeys = append(minesFoundKeys, key)
@@ -37,88 +44,158 @@ func printProgressStats(gamesByMinesFound map[int]int) {
 	sort.Ints(minesFoundKeys)
 
 	for _, key := range minesFoundKeys {
-		fmt.Printf("Mines found: %d, games: %d\n", key, gamesByMinesFound[key])
+		_, _ = fmt.Fprintf(w, "Mines found: %d, games: %d\n", key, gamesByMinesFound[key])
 	}
 }
 
 type gameResult struct {
+	GameId     string
+	Seed       int64
+	BoardSeed  int64 // seed the backend made the board with, 0 if it didn't say; see gameInformation
+	FirstClick engine.FirstClick
 	Status     string
 	MinesFound int
 	MinesTotal int
+	Moves      int // moves sent: cells opened, and flags and chords if the bot was asked to use them
+	Guesses    int // cells opened without being sure they're safe
+	Duration   time.Duration
+	FinalBoard string // see boardString
+	// ErrorKind and Error are set for games abandoned because of a backend error.
+	ErrorKind gameErrorKind
+	Error     string
 }
 
 func (gr gameResult) MinesFoundPercentage() float64 {
 	return float64(gr.MinesFound) / float64(gr.MinesTotal)
 }
 
-func playNewGame(client *swagger.APIClient) gameResult {
-	initialGame, _, err := client.DefaultApi.NewgamePost(context.Background())
+// playOptions are the optional extras of playNewGame.
+type playOptions struct {
+	// boardLog, if set, gets every move and the board after it
+	boardLog io.Writer
+	// record makes playNewGame return a recording of the game
+	record bool
+	// flagMines and chord are passed to planActions
+	flagMines bool
+	chord     bool
+	// board is the board to ask the backend for
+	board boardSpec
+	// opening is the first cell to open
+	opening opening
+}
+
+// playNewGame plays one game from start to finish. The recording is nil unless options ask for it.
+// If the backend fails or ctx is cancelled, the game is abandoned and the error is returned
+// along with the result of the game so far.
+func playNewGame(ctx context.Context, backend gameBackend, strategy Strategy, seed int64, options playOptions) (gameResult, *gameRecording, error) {
+	startedAt := time.Now()
+	initialGame, err := backend.NewGame(ctx, newGameRequest{Seed: seed, Board: options.board})
 	if err != nil {
-		panic(err)
+		return gameResult{Seed: seed, Duration: time.Since(startedAt)}, nil, err
+	}
+	var recording *gameRecording
+	if options.record {
+		recording = newGameRecording(seed, initialGame, backend.FirstClick())
 	}
 	gameInfo := newGameInfo(initialGame)
-	//fmt.Println(gameInfo.PrettyBoardState)
-	// initial move, guaranteed safe
-	initialCell := location{
-		X: int(gameInfo.BoardWidth / 2),
-		Y: int(gameInfo.BoardHeight / 2),
+	gameInfo.seed = seed
+	gameInfo.boardSeed = initialGame.Seed
+	gameInfo.firstClick = backend.FirstClick()
+	gameInfo.verbose = options.boardLog != nil
+	boardLog := options.boardLog
+	movesToMake, err := openingMoves(&gameInfo, options.opening, backend.FirstClick())
+	if err != nil {
+		result := gameInfo.Result()
+		result.Duration = time.Since(startedAt)
+		return result, recording, gameError{Kind: errorIllegalMove, Op: "move", Err: err}
 	}
-	gameInfo.queueCellToOpen(initialCell)
-	var currentTurnNumber int
 
 	for {
-		gameInfo.addFullyRevealedLocations()
-
-		for len(gameInfo.cellsToOpen) > 0 {
-			cell := gameInfo.cellsToOpen[0]
-			gameInfo.cellsToOpen = gameInfo.cellsToOpen[1:]
-
-			if gameInfo.fetchCell(cell.X, cell.Y) != "?" {
+		for _, planned := range movesToMake {
+			cell := planned.Cell
+			if gameInfo.isPlayed(planned) {
 				continue
 			}
-			*gameInfo.Game = move(client, gameInfo, cell)
+			moveInfo := swagger.MoveInfo{GameId: gameInfo.GameId, X: int32(cell.X), Y: int32(cell.Y), Action: planned.Action}
+			if err := ctx.Err(); err != nil {
+				result := gameInfo.Result()
+				result.Duration = time.Since(startedAt)
+				return result, recording, err
+			}
+			newGameState, err := move(ctx, backend, moveInfo)
+			if err != nil {
+				result := gameInfo.Result()
+				result.Duration = time.Since(startedAt)
+				return result, recording, err
+			}
+			gameInfo.movesMade++
+			reason := reasonSafe
+			switch {
+			case planned.Action == swagger.MoveActionFlag:
+				reason = reasonFlag
+			case planned.Action == swagger.MoveActionChord:
+				reason = reasonChord
+			case planned.Guess:
+				gameInfo.guessesTaken++
+				reason = reasonGuess
+			case gameInfo.movesMade == 1:
+				reason = reasonOpening
+			}
+			if recording != nil {
+				recording.addStep(moveInfo, planned, reason, newGameState)
+			}
+			gameInfo.receive(newGameState)
+			if gameInfo.verbose {
+				_, _ = fmt.Fprintf(boardLog, "game %s, turn %d, %s (%d, %d)\n", gameInfo.GameId, gameInfo.movesMade, actionName(moveInfo), cell.X, cell.Y)
+			}
 
 			if gameInfo.IsFinished() {
-				//fmt.Println(gameInfo.PrettyBoardState)
-				//fmt.Println("returning due to", gameInfo.Status)
-				return gameInfo.Result()
+				if gameInfo.verbose {
+					printBoardState(boardLog, gameInfo)
+					_, _ = fmt.Fprintf(boardLog, "game %s: %s\n", gameInfo.GameId, gameInfo.Status)
+				}
+				result := gameInfo.Result()
+				result.Duration = time.Since(startedAt)
+				return result, recording, nil
 			}
-			//fmt.Printf("turn %d, opening (%d, %d) from queue\n", currentTurnNumber, cell.X, cell.Y)
 
-			gameInfo.refreshBombs()
-			//printBoardState(os.Stdout, gameInfo)
-			currentTurnNumber++
+			if gameInfo.verbose {
+				printBoardState(boardLog, gameInfo)
+			}
 		}
 
-		gameInfo.findSafeCells()
-
-		if len(gameInfo.cellsToOpen) == 0 {
-			loc, err := gameInfo.findLeastRiskyCell()
-			if err != nil {
-				return gameResult{
-					Status:     "unsure",
-					MinesFound: gameInfo.NumberOfCorrectlyGuessedBombs(),
-					MinesTotal: int(gameInfo.MinesCount),
-				}
-			}
-			gameInfo.queueCellToOpen(loc)
+		movesToMake, err = strategy.NextMoves(&gameInfo)
+		if err != nil {
+			result := gameInfo.Result()
+			result.Status = "unsure"
+			result.Duration = time.Since(startedAt)
+			return result, recording, nil
 		}
+		movesToMake = gameInfo.planActions(movesToMake, options.flagMines, options.chord)
 	}
 }
 
-func move(client *swagger.APIClient, game gameInformation, cell location) swagger.Game {
-	newGameState, _, err := client.DefaultApi.MovePost(context.Background(), swagger.MoveInfo{
-		GameId: game.GameId,
-		X:      int32(cell.X),
-		Y:      int32(cell.Y),
-	})
-	if err != nil {
-		panic(err)
+// actionName is the action of a move, spelled out.
+func actionName(moveInfo swagger.MoveInfo) string {
+	if moveInfo.Action == "" {
+		return swagger.MoveActionOpen
 	}
-	return newGameState
+	return moveInfo.Action
+}
+
+func move(ctx context.Context, backend gameBackend, moveInfo swagger.MoveInfo) (swagger.Game, error) {
+	return backend.Move(ctx, moveInfo)
 }
 
 func printBoardState(w io.Writer, game gameInformation) {
+	printAnnotatedBoardState(w, game, func(loc location, cellState string) string {
+		return colored(cellState)
+	})
+}
+
+// printAnnotatedBoardState draws the board like printBoardState, but every cell is drawn with
+// whatever cell returns for it. It must take up a single column on screen.
+func printAnnotatedBoardState(w io.Writer, game gameInformation, cell func(loc location, cellState string) string) {
 	leftTopCorner := "\u250c"
 	rightTopCorner := "\u2510"
 	leftBottomCorner := "\u2514"
@@ -143,7 +220,7 @@ func printBoardState(w io.Writer, game gameInformation) {
 		_, _ = fmt.Fprintf(w, "%2d%s", i, verticalLine)
 		for j := 0; j < int(game.BoardWidth); j++ {
 			idx := j + i*int(game.BoardWidth)
-			_, _ = fmt.Fprint(w, colored(game.BoardState[idx]))
+			_, _ = fmt.Fprint(w, cell(location{j, i}, game.BoardState[idx]))
 			_, _ = fmt.Fprint(w, " ")
 		}
 		_, _ = fmt.Fprintf(w, "%s%d\n", verticalLine, i)
@@ -169,6 +246,8 @@ func colored(str string) string {
 		return fmt.Sprintf("\u001b[38;5;242m%s\u001b[0m", str)
 	} else if str == "*" {
 		return fmt.Sprintf("\u001b[31m%s\u001b[0m", str)
+	} else if str == flaggedCell {
+		return fmt.Sprintf("\u001b[33m%s\u001b[0m", str)
 	} else { // digit?
 		return fmt.Sprintf("\u001b[38;5;159m%s\u001b[0m", str)
 	}
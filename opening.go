@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"minesweeper-bot/engine"
+	"strconv"
+	"strings"
+)
+
+// Kinds of opening. All but openingCell depend on the size of the board.
+const (
+	openingCentre = "centre" // the centre cell, the one minesweeper-server keeps free of mines
+	openingCorner = "corner" // the top left corner
+	openingEdge   = "edge"   // the middle of the top edge
+	openingCell   = "cell"   // a fixed cell
+)
+
+var openingKinds = []string{openingCentre, openingCorner, openingEdge}
+
+// openingUsage is the help text of the flags that pick an opening.
+var openingUsage = fmt.Sprintf("first cell to open, one of %v or a cell as \"x,y\"", openingKinds)
+
+// opening is the first cell the bot opens in a game. The zero value is the centre.
+type opening struct {
+	kind string
+	// cell is the cell of an openingCell opening
+	cell location
+}
+
+// parseOpening reads one of openingKinds, or a cell as "x,y".
+func parseOpening(text string) (opening, error) {
+	kind := strings.ToLower(strings.TrimSpace(text))
+	if kind == "center" {
+		kind = openingCentre
+	}
+	if contains(openingKinds, kind) {
+		return opening{kind: kind}, nil
+	}
+	cell, err := parseCell(text)
+	if err != nil || cell.X < 0 || cell.Y < 0 {
+		return opening{}, fmt.Errorf("unknown opening %q, expected one of %v or a cell as \"x,y\"", text, openingKinds)
+	}
+	return opening{kind: openingCell, cell: cell}, nil
+}
+
+// splitOpenings splits a comma separated list of openings, keeping the two numbers of a cell
+// together: "corner,3,4,edge" is corner, the cell (3, 4) and edge.
+func splitOpenings(list string) []string {
+	isNumber := func(text string) bool {
+		_, err := strconv.Atoi(strings.TrimSpace(text))
+		return err == nil
+	}
+	fields := strings.Split(list, ",")
+	result := make([]string, 0, len(fields))
+	for i := 0; i < len(fields); i++ {
+		if i+1 < len(fields) && isNumber(fields[i]) && isNumber(fields[i+1]) {
+			result = append(result, fields[i]+","+fields[i+1])
+			i++
+			continue
+		}
+		result = append(result, fields[i])
+	}
+	return result
+}
+
+func (o opening) String() string {
+	switch o.kind {
+	case "":
+		return openingCentre
+	case openingCell:
+		return fmt.Sprintf("%d,%d", o.cell.X, o.cell.Y)
+	}
+	return o.kind
+}
+
+// location is the opening's cell on a board of the given size.
+func (o opening) location(width, height int) location {
+	switch o.kind {
+	case openingCorner:
+		return location{0, 0}
+	case openingEdge:
+		return location{width / 2, 0}
+	case openingCell:
+		return o.cell
+	}
+	return location{width / 2, height / 2}
+}
+
+// openingMoves are the first moves of a game on a backend that follows the given first click
+// rule. The opening is a guess unless the rule makes it safe, with the risk of any cell of a
+// board nothing is known about. If the rule only keeps the centre free and the opening is
+// elsewhere, the centre comes next, since it's still certain to be safe.
+func openingMoves(game *gameInformation, o opening, rule engine.FirstClick) ([]plannedMove, error) {
+	width, height := int(game.BoardWidth), int(game.BoardHeight)
+	cell := o.location(width, height)
+	if !game.grid.contains(cell) {
+		return nil, fmt.Errorf("opening (%d, %d) is outside of the %dx%d board", cell.X, cell.Y, width, height)
+	}
+	mines, cells := float64(game.MinesCount), float64(width*height)
+
+	switch rule {
+	case engine.FirstClickSafe, engine.FirstClickZero:
+		return []plannedMove{{Cell: cell}}, nil
+	case engine.FirstClickNone:
+		return []plannedMove{{Cell: cell, Guess: true, Risk: mines / cells}}, nil
+	}
+	centre := opening{}.location(width, height)
+	if cell == centre {
+		return []plannedMove{{Cell: cell}}, nil
+	}
+	return []plannedMove{{Cell: cell, Guess: true, Risk: mines / (cells - 1)}, {Cell: centre}}, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"minesweeper-bot/swagger"
+	"strconv"
+	"strings"
+)
+
+// parseBoard reads a board written out by hand, one row per line and one character per cell,
+// with the symbols of BoardState: "?" unknown, "*" or "F" mine, a digit for an open cell. Spaces
+// between cells are ignored, and rows may also be separated by "/" as in boardString. Blank
+// lines and lines starting with "#" are skipped, and a "mines: N" line gives the mine count:
+//
+//	# 1-2-1
+//	mines: 2
+//	???
+//	121
+func parseBoard(r io.Reader) (swagger.Game, error) {
+	var game swagger.Game
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if name, value, ok := cutField(line); ok {
+			if name != "mines" {
+				return swagger.Game{}, fmt.Errorf("line %d: unknown field %q, only \"mines\" is known", lineNumber, name)
+			}
+			mines, err := strconv.Atoi(value)
+			if err != nil || mines < 0 {
+				return swagger.Game{}, fmt.Errorf("line %d: mine count must be a number, got %q", lineNumber, value)
+			}
+			game.MinesCount = int32(mines)
+			continue
+		}
+
+		for _, row := range strings.Split(line, "/") {
+			cells := make([]string, 0, len(row))
+			for _, r := range row {
+				switch {
+				case r == ' ' || r == '\t':
+					continue
+				case r == '?' || r == '*' || r == 'F' || r >= '0' && r <= '8':
+					cells = append(cells, string(r))
+				default:
+					return swagger.Game{}, fmt.Errorf("line %d: unexpected %q, cells are \"?\", \"*\", \"F\" or 0 to 8", lineNumber, r)
+				}
+			}
+			if game.BoardHeight == 0 {
+				game.BoardWidth = int32(len(cells))
+			} else if len(cells) != int(game.BoardWidth) {
+				return swagger.Game{}, fmt.Errorf("line %d: row has %d cells, the ones above have %d", lineNumber, len(cells), game.BoardWidth)
+			}
+			game.BoardState = append(game.BoardState, cells...)
+			game.BoardHeight++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return swagger.Game{}, err
+	}
+	if game.BoardHeight == 0 {
+		return swagger.Game{}, fmt.Errorf("no board found")
+	}
+	return game, validateBoard(game)
+}
+
+// cutField splits a "name: value" or "name=value" line. Board rows never contain either.
+func cutField(line string) (name, value string, ok bool) {
+	i := strings.IndexAny(line, ":=")
+	if i < 0 {
+		return "", "", false
+	}
+	return strings.ToLower(strings.TrimSpace(line[:i])), strings.TrimSpace(line[i+1:]), true
+}
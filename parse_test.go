@@ -0,0 +1,46 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBoard(t *testing.T) {
+	text := "# 1-2-1\nmines: 2\n\n? ? ?\n1 2 1\n"
+	game, err := parseBoard(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.BoardWidth != 3 || game.BoardHeight != 2 || game.MinesCount != 2 {
+		t.Errorf("got a %dx%d board with %d mines, want 3x2 with 2", game.BoardWidth, game.BoardHeight, game.MinesCount)
+	}
+	if want := []string{"?", "?", "?", "1", "2", "1"}; !reflect.DeepEqual(game.BoardState, want) {
+		t.Errorf("cells are %v, want %v", game.BoardState, want)
+	}
+
+	// rows separated by "/", as boardString writes them
+	game, err = parseBoard(strings.NewReader("*1/11"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := boardString(&game); got != "*1/11" {
+		t.Errorf("read \"*1/11\" as %q", got)
+	}
+}
+
+func TestParseBoardErrors(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"# nothing but a comment",
+		"??/?",
+		"?x?",
+		"9??",
+		"mines: lots\n???",
+		"size: 3\n???",
+	} {
+		if _, err := parseBoard(strings.NewReader(text)); err == nil {
+			t.Errorf("parseBoard(%q) didn't fail", text)
+		}
+	}
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// constraint says that exactly `mines` of `cells` contain a bomb. Every numbered cell that still
+// has unknown neighbours produces one.
+type constraint struct {
+	cells []int // offsets
+	mines int
+}
+
+// collectConstraints builds one constraint per numbered cell that still touches unknown cells.
+// Bombs already known around the cell are subtracted from its number, and cells already queued
+// to be opened are known to be safe, so they're left out.
+func (game *gameInformation) collectConstraints() []constraint {
+	g := &game.grid
+	game.addFullyRevealedLocations()
+	result := make([]constraint, 0)
+	for _, offset := range game.frontier.members() {
+		count, _ := g.number(offset)
+
+		unknowns := make([]int, 0, 8)
+		for _, n := range g.neighbours[offset] {
+			if g.cells[n] == gridUnknown && !game.queued.has(n) {
+				unknowns = append(unknowns, n)
+			}
+		}
+		if len(unknowns) == 0 {
+			continue
+		}
+		result = append(result, constraint{
+			cells: unknowns,
+			mines: count - g.countAround(offset, gridBomb),
+		})
+	}
+	return result
+}
+
+// frontierComponent is a group of frontier cells linked by shared constraints. Components are
+// independent of each other except through the global mine count.
+type frontierComponent struct {
+	cells       []int // offsets
+	constraints []componentConstraint
+
+	// solutions[k] is the number of consistent assignments that place k mines in this component.
+	solutions []float64
+	// cellMines[k][i] is how many of those assignments put a mine into cells[i].
+	cellMines [][]float64
+	// approximate is set when the component was too big to enumerate, and solutions and
+	// cellMines only estimate the real counts, see estimate.
+	approximate bool
+}
+
+type componentConstraint struct {
+	cells []int // indices into frontierComponent.cells
+	mines int
+}
+
+// noProbability is what mineProbabilities gives for cells it has no estimate for.
+const noProbability = -1
+
+// mineProbabilities returns the probability of a bomb for every unknown cell on the board, by
+// offset. Open cells, bombs, queued cells, and interior cells when the mine count doesn't fit,
+// get noProbability.
+//
+// All mine assignments of the frontier (unknown cells next to a number) that satisfy every
+// number are enumerated. Each assignment is weighted by the number of ways to place the
+// remaining mines into the interior cells that don't touch any number, so every cell gets its
+// true marginal probability given the whole board.
+func (game *gameInformation) mineProbabilities() []float64 {
+	probabilities, _ := game.componentProbabilities(enumerateComponents(game.collectConstraints(), len(game.grid.cells)), game.queued)
+	return probabilities
+}
+
+// maxEnumerationSteps is how many cell assignments enumerateComponents tries at most, over all
+// the components. Most boards take a few thousand, but the count grows exponentially with the
+// size of a component, and a long enough frontier with few numbers to cut it short would take
+// hours.
+const maxEnumerationSteps = 1 << 21
+
+// enumerateComponents splits constraints into components and enumerates every one of them,
+// smallest first. Once maxEnumerationSteps are spent, the components that are left, the biggest
+// ones, are only estimated.
+func enumerateComponents(constraints []constraint, cells int) []*frontierComponent {
+	components := splitIntoComponents(constraints, cells)
+	bySize := append([]*frontierComponent(nil), components...)
+	sort.SliceStable(bySize, func(i, j int) bool { return len(bySize[i].cells) < len(bySize[j].cells) })
+	steps := maxEnumerationSteps
+	for _, component := range bySize {
+		if !component.enumerate(&steps) {
+			component.estimate()
+		}
+	}
+	return components
+}
+
+// componentProbabilities does the work of mineProbabilities for any enumerated components, with
+// the cells in safe known to hold no bomb. It also returns the natural log of the number of
+// boards that fit, or of the number of frontier assignments when the mine count isn't known.
+// That's -Inf when nothing fits at all, and when the frontier can't be completed with the mines
+// left: the probabilities fall back to ignoring the mine count then, but such a board is
+// impossible. Counts from different calls can be compared, which is how progressChance tells how
+// likely every number a cell could show is.
+func (game *gameInformation) componentProbabilities(components []*frontierComponent, safe bitset) ([]float64, float64) {
+	g := &game.grid
+	frontier := newBitset(len(g.cells))
+	frontierCells := 0
+	for _, component := range components {
+		for _, offset := range component.cells {
+			frontier.add(offset)
+		}
+		frontierCells += len(component.cells)
+	}
+	interior := make([]int, 0)
+	for offset, value := range g.cells {
+		if value == gridUnknown && !frontier.has(offset) && !safe.has(offset) {
+			interior = append(interior, offset)
+		}
+	}
+	minesLeft := game.minesLeft()
+
+	// weights[k] is the relative number of ways to complete a board whose frontier holds k mines.
+	weights, logScale := interiorWeights(len(interior), minesLeft, frontierCells)
+	total := 0.0
+	all := convolveSolutions(components, -1)
+	for k, ways := range all {
+		total += ways * weights[k]
+	}
+	if total == 0 {
+		// the mine count doesn't fit the board (or the server didn't report it);
+		// fall back to treating every frontier configuration as equally likely.
+		for k := range weights {
+			weights[k] = 1
+		}
+		minesLeft = -1
+		logScale = 0
+		if game.MinesCount > 0 {
+			logScale = math.Inf(-1)
+		}
+		total = 0
+		for _, ways := range all {
+			total += ways
+		}
+	}
+
+	result := make([]float64, len(g.cells))
+	for offset := range result {
+		result[offset] = noProbability
+	}
+	if total == 0 {
+		return result, math.Inf(-1)
+	}
+
+	for i, component := range components {
+		for _, offset := range component.cells {
+			result[offset] = 0
+		}
+		others := convolveSolutions(components, i)
+		for own, cellMines := range component.cellMines {
+			if component.solutions[own] == 0 {
+				continue
+			}
+			completions := 0.0
+			for k, ways := range others {
+				if own+k < len(weights) {
+					completions += ways * weights[own+k]
+				}
+			}
+			for c, offset := range component.cells {
+				result[offset] += cellMines[c] * completions / total
+			}
+		}
+		if component.approximate {
+			// an estimate is never sure enough to open or flag a cell on
+			for _, offset := range component.cells {
+				result[offset] = math.Max(2*certaintyEpsilon, math.Min(1-2*certaintyEpsilon, result[offset]))
+			}
+		}
+	}
+
+	if len(interior) > 0 && minesLeft >= 0 {
+		expected := 0.0
+		for k, ways := range all {
+			if k <= minesLeft {
+				expected += ways * weights[k] * float64(minesLeft-k)
+			}
+		}
+		risk := expected / total / float64(len(interior))
+		for _, offset := range interior {
+			result[offset] = risk
+		}
+	}
+	return result, math.Log(total) + logScale
+}
+
+// interiorWeights returns, for every possible number of mines on the frontier, a value
+// proportional to the number of ways to spread the rest of the mines over the interior cells,
+// and the log of the factor they were scaled down by.
+func interiorWeights(interiorCells, minesLeft, frontierCells int) ([]float64, float64) {
+	weights := make([]float64, frontierCells+1)
+	logWays := make([]float64, frontierCells+1)
+	maxLogWays := math.Inf(-1)
+	for k := range weights {
+		rest := minesLeft - k
+		if rest < 0 || rest > interiorCells {
+			logWays[k] = math.Inf(-1)
+			continue
+		}
+		logWays[k] = logBinomial(interiorCells, rest)
+		if logWays[k] > maxLogWays {
+			maxLogWays = logWays[k]
+		}
+	}
+	if math.IsInf(maxLogWays, -1) {
+		return weights, 0
+	}
+	// binomials overflow float64 on big boards, so only their ratios are kept
+	for k := range weights {
+		weights[k] = math.Exp(logWays[k] - maxLogWays)
+	}
+	return weights, maxLogWays
+}
+
+func logBinomial(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
+
+// convolveSolutions combines per-component solution counts into counts by total number of
+// frontier mines. The component at index `skip` is left out (pass -1 to use all of them).
+func convolveSolutions(components []*frontierComponent, skip int) []float64 {
+	result := []float64{1}
+	for i, component := range components {
+		if i == skip {
+			continue
+		}
+		next := make([]float64, len(result)+len(component.solutions)-1)
+		for a, waysA := range result {
+			if waysA == 0 {
+				continue
+			}
+			for b, waysB := range component.solutions {
+				next[a+b] += waysA * waysB
+			}
+		}
+		result = next
+	}
+	return result
+}
+
+// splitIntoComponents groups constraints that share cells, so that each group can be
+// enumerated on its own. cells is the size of the board.
+func splitIntoComponents(constraints []constraint, cells int) []*frontierComponent {
+	parent := make([]int, len(constraints))
+	for i := range parent {
+		parent[i] = i
+	}
+	find := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+
+	// owner[offset] is the first constraint on the cell, indexes[offset] its index in its
+	// component; -1 until there's one
+	owner := make([]int, cells)
+	indexes := make([]int, cells)
+	for offset := range owner {
+		owner[offset] = -1
+		indexes[offset] = -1
+	}
+	for i, c := range constraints {
+		for _, offset := range c.cells {
+			if j := owner[offset]; j >= 0 {
+				parent[find(i)] = find(j)
+			} else {
+				owner[offset] = i
+			}
+		}
+	}
+
+	byRoot := make(map[int]*frontierComponent)
+	result := make([]*frontierComponent, 0)
+	for i, c := range constraints {
+		root := find(i)
+		component, ok := byRoot[root]
+		if !ok {
+			component = &frontierComponent{}
+			byRoot[root] = component
+			result = append(result, component)
+		}
+		cc := componentConstraint{mines: c.mines, cells: make([]int, 0, len(c.cells))}
+		for _, offset := range c.cells {
+			idx := indexes[offset]
+			if idx < 0 {
+				idx = len(component.cells)
+				indexes[offset] = idx
+				component.cells = append(component.cells, offset)
+			}
+			cc.cells = append(cc.cells, idx)
+		}
+		component.constraints = append(component.constraints, cc)
+	}
+	return result
+}
+
+// offsetConstraints returns the component's constraints the way collectConstraints made them.
+func (component *frontierComponent) offsetConstraints() []constraint {
+	result := make([]constraint, 0, len(component.constraints))
+	for _, cc := range component.constraints {
+		c := constraint{cells: make([]int, 0, len(cc.cells)), mines: cc.mines}
+		for _, idx := range cc.cells {
+			c.cells = append(c.cells, component.cells[idx])
+		}
+		result = append(result, c)
+	}
+	return result
+}
+
+// newCounts makes room for the counts of every number of mines the component can hold.
+func (component *frontierComponent) newCounts() {
+	n := len(component.cells)
+	component.solutions = make([]float64, n+1)
+	component.cellMines = make([][]float64, n+1)
+	for k := range component.cellMines {
+		component.cellMines[k] = make([]float64, n)
+	}
+}
+
+// enumerate counts every mine assignment of the component that satisfies all its constraints.
+// Cells are assigned in the order they were discovered, which follows the constraints around the
+// frontier, so contradictions are usually spotted after a few cells. Every cell assigned takes
+// one of steps; enumerate gives up and returns false when they run out.
+func (component *frontierComponent) enumerate(steps *int) bool {
+	n := len(component.cells)
+	component.newCounts()
+
+	// for every cell, the constraints it takes part in
+	cellConstraints := make([][]int, n)
+	for i, c := range component.constraints {
+		for _, cell := range c.cells {
+			cellConstraints[cell] = append(cellConstraints[cell], i)
+		}
+	}
+	// mines placed so far and cells still unassigned, per constraint
+	placed := make([]int, len(component.constraints))
+	open := make([]int, len(component.constraints))
+	for i, c := range component.constraints {
+		open[i] = len(c.cells)
+	}
+	assignment := make([]bool, n)
+
+	var walk func(cell, mines int)
+	walk = func(cell, mines int) {
+		if *steps <= 0 {
+			return
+		}
+		*steps--
+		if cell == n {
+			component.solutions[mines]++
+			for i, isMine := range assignment {
+				if isMine {
+					component.cellMines[mines][i]++
+				}
+			}
+			return
+		}
+
+		for _, isMine := range []bool{false, true} {
+			ok := true
+			for _, ci := range cellConstraints[cell] {
+				open[ci]--
+				if isMine {
+					placed[ci]++
+				}
+				need := component.constraints[ci].mines
+				if placed[ci] > need || placed[ci]+open[ci] < need {
+					ok = false
+				}
+			}
+			if ok {
+				assignment[cell] = isMine
+				added := 0
+				if isMine {
+					added = 1
+				}
+				walk(cell+1, mines+added)
+				assignment[cell] = false
+			}
+			for _, ci := range cellConstraints[cell] {
+				open[ci]++
+				if isMine {
+					placed[ci]--
+				}
+			}
+		}
+	}
+	walk(0, 0)
+	return *steps > 0
+}
+
+// estimate stands in for enumerate on a component too big to enumerate. Every cell's chance of
+// a mine is taken to be the average share of mines left to place among the constraints it's
+// part of, and the cells are treated as independent. It's rough, but it still ranks guesses
+// sensibly, and componentProbabilities makes sure the solver never takes it for certain.
+func (component *frontierComponent) estimate() {
+	n := len(component.cells)
+	component.newCounts()
+	component.approximate = true
+
+	chances := make([]float64, n)
+	counts := make([]int, n)
+	for _, c := range component.constraints {
+		for _, cell := range c.cells {
+			chances[cell] += float64(c.mines) / float64(len(c.cells))
+			counts[cell]++
+		}
+	}
+	expected := 0.0
+	for i := range chances {
+		chances[i] /= float64(counts[i])
+		expected += chances[i]
+	}
+
+	// solutions[k] is the chance of k mines in all, one cell at a time
+	component.solutions[0] = 1
+	for i, chance := range chances {
+		for k := i + 1; k > 0; k-- {
+			component.solutions[k] = component.solutions[k]*(1-chance) + component.solutions[k-1]*chance
+		}
+		component.solutions[0] *= 1 - chance
+	}
+	// no constraint can take more mines than the component holds, or leave out more safe cells,
+	// and componentProbabilities relies on impossible totals having no solutions
+	least, most := 0, n
+	for _, c := range component.constraints {
+		if c.mines > least {
+			least = c.mines
+		}
+		if room := n - len(c.cells) + c.mines; room < most {
+			most = room
+		}
+	}
+	for k := range component.solutions {
+		if k < least || k > most {
+			component.solutions[k] = 0
+		}
+	}
+	if expected == 0 {
+		return
+	}
+	// given k mines in all, each cell gets its share of them, which keeps its overall chance
+	for k, ways := range component.solutions {
+		for i, chance := range chances {
+			component.cellMines[k][i] = ways * math.Min(1, chance*float64(k)/expected)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// A board the mine count can't complete is impossible, but mineProbabilities still has to give
+// something to go on.
+func TestComponentProbabilitiesMineCountDoesNotFit(t *testing.T) {
+	game, err := parseBoard(strings.NewReader("mines: 1\n???/?2?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gameInfo := newGameInfo(game)
+	components := enumerateComponents(gameInfo.collectConstraints(), len(gameInfo.grid.cells))
+	probabilities, logTotal := gameInfo.componentProbabilities(components, gameInfo.queued)
+	if !math.IsInf(logTotal, -1) {
+		t.Errorf("log of the number of boards is %v, want -Inf", logTotal)
+	}
+	for offset, probability := range probabilities {
+		if gameInfo.grid.cells[offset] == gridUnknown && math.Abs(probability-0.4) > 1e-9 {
+			t.Errorf("chance of a mine in %v is %v, want 0.4 from the number alone", gameInfo.grid.location(offset), probability)
+		}
+	}
+
+	game.MinesCount = 0
+	gameInfo = newGameInfo(game)
+	components = enumerateComponents(gameInfo.collectConstraints(), len(gameInfo.grid.cells))
+	if _, logTotal := gameInfo.componentProbabilities(components, gameInfo.queued); math.Abs(logTotal-math.Log(10)) > 1e-9 {
+		t.Errorf("with the mine count unknown, log of the number of assignments is %v, want log(10)", logTotal)
+	}
+}
+
+// A component too big to enumerate mustn't leave the small ones after it with only estimates.
+func TestEnumerateComponentsSmallAfterBig(t *testing.T) {
+	rows := []string{strings.Repeat("?", 40), strings.Repeat("4?", 20), strings.Repeat("?", 40)}
+	game, err := parseBoard(strings.NewReader(strings.Join(rows, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gameInfo := newGameInfo(game)
+	cells := len(gameInfo.grid.cells)
+	// one mine in two cells of their own, past the end of the board
+	constraints := append(gameInfo.collectConstraints(), constraint{cells: []int{cells, cells + 1}, mines: 1})
+
+	components := enumerateComponents(constraints, cells+2)
+	if len(components) != 2 {
+		t.Fatalf("got %d components, want 2", len(components))
+	}
+	big, small := components[0], components[1]
+	if !big.approximate {
+		t.Errorf("the long frontier was enumerated, it's meant to be too big for that")
+	}
+	if small.approximate {
+		t.Errorf("the two cell component was only estimated")
+	}
+	if want := []float64{0, 2, 0}; !reflect.DeepEqual(small.solutions, want) {
+		t.Errorf("the two cell component has solutions %v, want %v", small.solutions, want)
+	}
+}
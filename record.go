@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"minesweeper-bot/engine"
+	"minesweeper-bot/swagger"
+	"path/filepath"
+)
+
+// recordingVersion is bumped whenever gameRecording changes in a way older readers can't handle.
+// Version 2 added flag and chord moves, which version 1 readers would replay as opening cells.
+const recordingVersion = 2
+
+// Reasons for a move in a recording.
+const (
+	reasonOpening = "opening" // the first click of the game
+	reasonSafe    = "safe"    // a cell the strategy deduced to be safe
+	reasonGuess   = "guess"   // a cell that may hold a bomb, see recordedStep.Risk
+	reasonFlag    = "flag"    // a cell deduced to hold a bomb
+	reasonChord   = "chord"   // a number whose bombs are all flagged
+)
+
+// gameRecording is everything that happened in one game, enough to look at every board again
+// later or to replay the moves on a board generated from the same seed.
+type gameRecording struct {
+	Version int   `json:"version"`
+	Seed    int64 `json:"seed"`
+	// FirstClick is the backend's engine.FirstClick rule, empty for the centre rule of
+	// minesweeper-server. Where the mines are depends on it.
+	FirstClick string         `json:"first_click,omitempty"`
+	Initial    swagger.Game   `json:"initial"`
+	Steps      []recordedStep `json:"steps"`
+}
+
+// recordedStep is a move sent to the server and the board it answered with.
+type recordedStep struct {
+	Move   swagger.MoveInfo `json:"move"`
+	Reason string           `json:"reason"`
+	// Risk is the chance of a bomb the strategy estimated for a guess.
+	Risk  float64      `json:"risk,omitempty"`
+	Board swagger.Game `json:"board"`
+}
+
+func newGameRecording(seed int64, initial swagger.Game, firstClick engine.FirstClick) *gameRecording {
+	recording := &gameRecording{
+		Version: recordingVersion,
+		Seed:    seed,
+		Initial: copyGame(initial),
+		Steps:   make([]recordedStep, 0),
+	}
+	if firstClick != engine.FirstClickCentre {
+		recording.FirstClick = string(firstClick)
+	}
+	return recording
+}
+
+func (recording *gameRecording) addStep(moveInfo swagger.MoveInfo, planned plannedMove, reason string, board swagger.Game) {
+	step := recordedStep{Move: moveInfo, Reason: reason, Board: copyGame(board)}
+	if reason == reasonGuess {
+		step.Risk = planned.Risk
+	}
+	recording.Steps = append(recording.Steps, step)
+}
+
+// copyGame copies the board state, which the solver overwrites as it marks bombs.
+func copyGame(game swagger.Game) swagger.Game {
+	game.BoardState = append([]string(nil), game.BoardState...)
+	return game
+}
+
+func (recording *gameRecording) save(dir string) error {
+	data, err := json.MarshalIndent(recording, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("seed-%d.json", recording.Seed)
+	return ioutil.WriteFile(filepath.Join(dir, name), data, 0644)
+}
+
+func loadGameRecording(path string) (*gameRecording, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var recording gameRecording
+	if err := json.Unmarshal(data, &recording); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if recording.Version < 1 || recording.Version > recordingVersion {
+		return nil, fmt.Errorf("%s: unsupported recording version %d, this bot reads versions 1 to %d", path, recording.Version, recordingVersion)
+	}
+	return &recording, nil
+}
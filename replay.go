@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"minesweeper-bot/engine"
+	"minesweeper-bot/swagger"
+	"os"
+	"strings"
+)
+
+func replayCommand(args []string) error {
+	flags := newFlagSet("replay")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s replay [flags] <recording.json>\n", programName())
+		flags.PrintDefaults()
+	}
+	step := flags.Bool("step", false, "wait for Enter before every move")
+	verify := flags.Bool("verify", false, "also play the recorded moves on the local engine with the recorded seed and check that it answers with the same boards")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usageError{"replay needs exactly one recording file"}
+	}
+
+	recording, err := loadGameRecording(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var pause func()
+	if *step {
+		stdin := bufio.NewReader(os.Stdin)
+		pause = func() {
+			fmt.Print("press Enter for the next move")
+			_, _ = stdin.ReadString('\n')
+		}
+	}
+	replayRecording(os.Stdout, recording, pause)
+
+	if *verify {
+		return verifyRecording(recording)
+	}
+	return nil
+}
+
+// replayRecording prints every board of a recorded game, with the move that led to it. pause,
+// if set, is called before every move.
+func replayRecording(w io.Writer, recording *gameRecording, pause func()) {
+	_, _ = fmt.Fprintf(w, "game %s, seed %d, %dx%d with %d mines\n", recording.Initial.GameId, recording.Seed,
+		recording.Initial.BoardWidth, recording.Initial.BoardHeight, recording.Initial.MinesCount)
+	printBoardState(w, newGameInfo(copyGame(recording.Initial)))
+
+	for i, step := range recording.Steps {
+		if pause != nil {
+			pause()
+		}
+		_, _ = fmt.Fprintf(w, "move %d: %s (%d, %d), %s\n", i+1, actionName(step.Move), step.Move.X, step.Move.Y, describeReason(step))
+		printBoardState(w, newGameInfo(copyGame(step.Board)))
+		if step.Board.Status != "" {
+			_, _ = fmt.Fprintf(w, "game over: %s\n", step.Board.Status)
+		}
+	}
+}
+
+func describeReason(step recordedStep) string {
+	switch step.Reason {
+	case reasonGuess:
+		return fmt.Sprintf("guess with %.1f%% risk", step.Risk*100)
+	case reasonFlag:
+		return "it's a bomb"
+	case reasonChord:
+		return "its bombs are all flagged"
+	}
+	return step.Reason
+}
+
+// verifyRecording plays the recorded moves on a fresh board from the local engine with the same
+// seed, size and first click rule, and fails on the first board that differs from the recorded one.
+func verifyRecording(recording *gameRecording) error {
+	e := engine.New(engine.Config{
+		Width:      int(recording.Initial.BoardWidth),
+		Height:     int(recording.Initial.BoardHeight),
+		Mines:      int(recording.Initial.MinesCount),
+		FirstClick: engine.FirstClick(recording.FirstClick),
+	})
+	game, err := e.NewGame(recording.Seed)
+	if err != nil {
+		return err
+	}
+	for i, step := range recording.Steps {
+		moveInfo := step.Move
+		moveInfo.GameId = game.GameId
+		game, err = e.Move(moveInfo)
+		if err != nil {
+			return fmt.Errorf("move %d: %v", i+1, err)
+		}
+		if !sameBoard(game, step.Board) {
+			return fmt.Errorf("move %d: local engine answered with a different board than the recording:\n%s", i+1, game.PrettyBoardState)
+		}
+	}
+	fmt.Println("local engine reproduced every recorded board")
+	return nil
+}
+
+func sameBoard(a, b swagger.Game) bool {
+	return a.Status == b.Status && strings.Join(a.BoardState, ",") == strings.Join(b.BoardState, ",")
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	statistics "minesweeper-bot/stats"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reporter writes the results of a run as games finish and a summary at the end.
+type reporter interface {
+	gameFinished(result gameResult, stats *runStats) error
+	runFinished(stats *runStats) error
+}
+
+// newReporter returns a reporter for one of outputFormats. Games are written to out and the
+// summary to summaryOut, which may well be the same writer.
+func newReporter(format string, out io.Writer, summaryOut io.Writer) reporter {
+	switch format {
+	case "jsonl":
+		return jsonLinesReporter{games: json.NewEncoder(out), summary: json.NewEncoder(summaryOut)}
+	case "csv":
+		return &csvReporter{games: csv.NewWriter(out), summary: csv.NewWriter(summaryOut)}
+	default:
+		return textReporter{out: out, summaryOut: summaryOut}
+	}
+}
+
+// textReporter prints the running totals after every game and a histogram of mines found at the
+// end. It's meant for people, not for scripts.
+type textReporter struct {
+	out        io.Writer
+	summaryOut io.Writer
+}
+
+func (r textReporter) gameFinished(result gameResult, stats *runStats) error {
+	if result.Status == statusError {
+		if _, err := fmt.Fprintf(r.out, "game %s: %s, seed %d: %s\n", result.GameId, result.Status, result.Seed, result.Error); err != nil {
+			return err
+		}
+	} else if result.Status != "win" {
+		if _, err := fmt.Fprintf(r.out, "game %s: %s, seed %d\n", result.GameId, result.Status, result.Seed); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(r.out, stats.results)
+	return err
+}
+
+func (r textReporter) runFinished(stats *runStats) error {
+	printProgressStats(r.summaryOut, stats.progress)
+
+	winRate, interval := stats.winRate()
+	progress := statistics.Describe(stats.minesFoundPercentages)
+	guesses := statistics.Describe(stats.guessesPerGame)
+	_, _ = fmt.Fprintf(r.summaryOut, "win rate: %.1f%% (95%% CI %.1f%% to %.1f%%) in %d finished games\n",
+		winRate*100, interval.Low*100, interval.High*100, stats.finishedGames())
+	_, _ = fmt.Fprintf(r.summaryOut, "mines found: mean %.1f%%, median %.1f%%\n", progress.Mean*100, progress.Median*100)
+	_, _ = fmt.Fprintf(r.summaryOut, "guesses per game: mean %.2f, median %g, max %g; games by guesses: %s\n",
+		guesses.Mean, guesses.Median, guesses.Max, formatHistogram(stats.guessesHistogram()))
+	if len(stats.errors) > 0 {
+		_, err := fmt.Fprintf(r.summaryOut, "errored games by kind: %v\n", stats.errors)
+		return err
+	}
+	return nil
+}
+
+// gameRecord is a finished game in the machine-readable formats.
+type gameRecord struct {
+	Record     string  `json:"record"` // always "game"
+	GameId     string  `json:"game_id"`
+	Seed       int64   `json:"seed"`
+	BoardSeed  int64   `json:"board_seed"`
+	FirstClick string  `json:"first_click"`
+	Status     string  `json:"status"`
+	MinesFound int     `json:"mines_found"`
+	MinesTotal int     `json:"mines_total"`
+	Moves      int     `json:"moves"`
+	Guesses    int     `json:"guesses"`
+	DurationMs float64 `json:"duration_ms"`
+	FinalBoard string  `json:"final_board"`
+	ErrorKind  string  `json:"error_kind,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+func newGameRecord(result gameResult) gameRecord {
+	return gameRecord{
+		Record:     "game",
+		GameId:     result.GameId,
+		Seed:       result.Seed,
+		BoardSeed:  result.BoardSeed,
+		FirstClick: string(result.FirstClick),
+		Status:     result.Status,
+		MinesFound: result.MinesFound,
+		MinesTotal: result.MinesTotal,
+		Moves:      result.Moves,
+		Guesses:    result.Guesses,
+		DurationMs: float64(result.Duration) / float64(time.Millisecond),
+		FinalBoard: result.FinalBoard,
+		ErrorKind:  string(result.ErrorKind),
+		Error:      result.Error,
+	}
+}
+
+// summaryRecord is the aggregate of a whole run in the machine-readable formats.
+type summaryRecord struct {
+	Record            string         `json:"record"` // always "summary"
+	Games             int            `json:"games"`
+	Results           map[string]int `json:"results"`
+	Errors            map[string]int `json:"errors,omitempty"` // errored games by kind
+	WinRate           float64        `json:"win_rate"`
+	WinRateCILow      float64        `json:"win_rate_ci_low"` // 95% Wilson interval
+	WinRateCIHigh     float64        `json:"win_rate_ci_high"`
+	AverageProgress   float64        `json:"average_progress"` // mean share of mines found
+	MedianProgress    float64        `json:"median_progress"`
+	Moves             int            `json:"moves"`
+	Guesses           int            `json:"guesses"`
+	GuessesPerGame    float64        `json:"guesses_per_game"`
+	MedianGuesses     float64        `json:"median_guesses"`
+	AverageDurationMs float64        `json:"average_duration_ms"`
+	// Progress is the number of games by mines found.
+	Progress map[string]int `json:"progress"`
+	// GamesByGuesses is the number of games by guesses taken.
+	GamesByGuesses map[string]int `json:"games_by_guesses"`
+}
+
+func newSummaryRecord(stats *runStats) summaryRecord {
+	summary := summaryRecord{
+		Record:  "summary",
+		Games:   stats.games,
+		Results: stats.results,
+		Moves:   stats.moves,
+		Guesses: stats.guesses,
+	}
+	if len(stats.errors) > 0 {
+		summary.Errors = make(map[string]int)
+		for kind, games := range stats.errors {
+			summary.Errors[string(kind)] = games
+		}
+	}
+	// errored games didn't get to an end, so the averages leave them out
+	var interval statistics.Interval
+	summary.WinRate, interval = stats.winRate()
+	summary.WinRateCILow, summary.WinRateCIHigh = interval.Low, interval.High
+	progress := statistics.Describe(stats.minesFoundPercentages)
+	summary.AverageProgress, summary.MedianProgress = progress.Mean, progress.Median
+	guesses := statistics.Describe(stats.guessesPerGame)
+	summary.GuessesPerGame, summary.MedianGuesses = guesses.Mean, guesses.Median
+	if stats.finishedGames() > 0 {
+		summary.AverageDurationMs = float64(stats.duration) / float64(time.Millisecond) / float64(stats.finishedGames())
+	}
+	// JSON object keys are strings anyway
+	summary.Progress = stringKeys(stats.progress)
+	summary.GamesByGuesses = stringKeys(stats.guessesHistogram())
+	return summary
+}
+
+func stringKeys(histogram map[int]int) map[string]int {
+	result := make(map[string]int, len(histogram))
+	for key, count := range histogram {
+		result[strconv.Itoa(key)] = count
+	}
+	return result
+}
+
+// formatHistogram writes a histogram as "key:count" pairs ordered by key.
+func formatHistogram(histogram map[int]int) string {
+	keys := make([]int, 0, len(histogram))
+	for key := range histogram {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%d:%d", key, histogram[key]))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// jsonLinesReporter writes a JSON object per game and one for the summary, one per line. The
+// "record" field tells them apart.
+type jsonLinesReporter struct {
+	games   *json.Encoder
+	summary *json.Encoder
+}
+
+func (r jsonLinesReporter) gameFinished(result gameResult, stats *runStats) error {
+	return r.games.Encode(newGameRecord(result))
+}
+
+func (r jsonLinesReporter) runFinished(stats *runStats) error {
+	return r.summary.Encode(newSummaryRecord(stats))
+}
+
+// csvReporter writes a row per game, and the summary as a separate one-row table.
+type csvReporter struct {
+	games         *csv.Writer
+	summary       *csv.Writer
+	headerWritten bool
+}
+
+func (r *csvReporter) gameFinished(result gameResult, stats *runStats) error {
+	if !r.headerWritten {
+		r.headerWritten = true
+		err := r.games.Write([]string{"game_id", "seed", "board_seed", "first_click", "status", "mines_found", "mines_total", "moves", "guesses", "duration_ms", "final_board", "error_kind", "error"})
+		if err != nil {
+			return err
+		}
+	}
+	record := newGameRecord(result)
+	err := r.games.Write([]string{
+		record.GameId,
+		strconv.FormatInt(record.Seed, 10),
+		strconv.FormatInt(record.BoardSeed, 10),
+		record.FirstClick,
+		record.Status,
+		strconv.Itoa(record.MinesFound),
+		strconv.Itoa(record.MinesTotal),
+		strconv.Itoa(record.Moves),
+		strconv.Itoa(record.Guesses),
+		formatFloat(record.DurationMs),
+		record.FinalBoard,
+		record.ErrorKind,
+		record.Error,
+	})
+	if err != nil {
+		return err
+	}
+	r.games.Flush()
+	return r.games.Error()
+}
+
+func (r *csvReporter) runFinished(stats *runStats) error {
+	summary := newSummaryRecord(stats)
+	statuses := make([]string, 0, len(summary.Results))
+	for status := range summary.Results {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	results := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		results = append(results, fmt.Sprintf("%s:%d", status, summary.Results[status]))
+	}
+
+	errors := make([]string, 0, len(summary.Errors))
+	for kind, games := range summary.Errors {
+		errors = append(errors, fmt.Sprintf("%s:%d", kind, games))
+	}
+	sort.Strings(errors)
+
+	_ = r.summary.Write([]string{"games", "results", "errors", "win_rate", "win_rate_ci_low", "win_rate_ci_high",
+		"average_progress", "median_progress", "moves", "guesses", "guesses_per_game", "median_guesses",
+		"average_duration_ms", "progress", "games_by_guesses"})
+	_ = r.summary.Write([]string{
+		strconv.Itoa(summary.Games),
+		strings.Join(results, " "),
+		strings.Join(errors, " "),
+		formatFloat(summary.WinRate),
+		formatFloat(summary.WinRateCILow),
+		formatFloat(summary.WinRateCIHigh),
+		formatFloat(summary.AverageProgress),
+		formatFloat(summary.MedianProgress),
+		strconv.Itoa(summary.Moves),
+		strconv.Itoa(summary.Guesses),
+		formatFloat(summary.GuessesPerGame),
+		formatFloat(summary.MedianGuesses),
+		formatFloat(summary.AverageDurationMs),
+		formatHistogram(stats.progress),
+		formatHistogram(stats.guessesHistogram()),
+	})
+	r.summary.Flush()
+	return r.summary.Error()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	statistics "minesweeper-bot/stats"
+	"os"
+	"sync"
+	"time"
+)
+
+// runner plays a batch of games on several workers at once. Game N of a batch is always played
+// with seed firstSeed+N, no matter which worker picks it up or when.
+type runner struct {
+	backend  gameBackend
+	strategy Strategy
+	workers  int
+
+	// boardLog, if set, gets every move and board of every game. Games are written out whole
+	// when they finish, so concurrent games don't mix.
+	boardLog io.Writer
+	// recordDir, if set, is where a recording of every game is saved, named after its seed.
+	recordDir string
+	// flagMines and chord make the bot flag bombs and chord, see planActions.
+	flagMines bool
+	chord     bool
+	// board is the board every game is played on.
+	board boardSpec
+	// opening is the first cell opened in every game.
+	opening opening
+}
+
+const (
+	// statusError is the status of games abandoned because the backend failed.
+	statusError = "error"
+	// statusAborted is the status of games abandoned because the run was cancelled.
+	statusAborted = "aborted"
+)
+
+// runStats is what a batch of games adds up to. Everything reported from it is a count or a
+// statistic of a whole sample, so it comes out the same whatever order the games finish in.
+// Workers only touch it while holding the runner's lock.
+type runStats struct {
+	results  map[string]int        // games by status
+	progress map[int]int           // games by mines found
+	errors   map[gameErrorKind]int // errored games by kind of error
+
+	games    int
+	moves    int
+	guesses  int
+	duration time.Duration
+
+	// per finished game
+	minesFoundPercentages []float64
+	guessesPerGame        []float64
+}
+
+func newRunStats() *runStats {
+	return &runStats{
+		results:  make(map[string]int),
+		progress: make(map[int]int),
+		errors:   make(map[gameErrorKind]int),
+	}
+}
+
+// finishedGames is the number of games that got to an end, i.e. all but the errored and
+// aborted ones.
+func (stats *runStats) finishedGames() int {
+	return stats.games - stats.results[statusError] - stats.results[statusAborted]
+}
+
+func (stats *runStats) add(result gameResult) {
+	stats.results[result.Status]++
+	stats.games++
+	if result.Status == statusError {
+		stats.errors[result.ErrorKind]++
+	}
+	if !isFinished(result) {
+		// the game didn't get to an end, so it says nothing about the solver
+		return
+	}
+	stats.progress[result.MinesFound]++
+	stats.moves += result.Moves
+	stats.guesses += result.Guesses
+	stats.duration += result.Duration
+	stats.guessesPerGame = append(stats.guessesPerGame, float64(result.Guesses))
+	if result.MinesTotal > 0 {
+		stats.minesFoundPercentages = append(stats.minesFoundPercentages, result.MinesFoundPercentage())
+	}
+}
+
+// winRate is the share of finished games won, with its 95% confidence interval.
+func (stats *runStats) winRate() (float64, statistics.Interval) {
+	wins, games := stats.results["win"], stats.finishedGames()
+	if games == 0 {
+		return 0, statistics.Wilson(0, 0, statistics.Z95)
+	}
+	return float64(wins) / float64(games), statistics.Wilson(wins, games, statistics.Z95)
+}
+
+// guessesHistogram is the number of finished games by guesses taken.
+func (stats *runStats) guessesHistogram() map[int]int {
+	guesses := make([]int, len(stats.guessesPerGame))
+	for i, g := range stats.guessesPerGame {
+		guesses[i] = int(g)
+	}
+	return statistics.Histogram(guesses)
+}
+
+// run plays `games` games and returns their statistics. onResult, if set, is called after every
+// game, one call at a time, with stats already including that game.
+//
+// Once ctx is cancelled no new games are started, and the games in progress are abandoned after
+// their current move. They're reported as aborted, and run returns the statistics so far.
+func (r runner) run(ctx context.Context, games int, firstSeed int64, onResult func(result gameResult, stats *runStats)) *runStats {
+	workers := r.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	seeds := make(chan int64)
+	go func() {
+		defer close(seeds)
+		for i := 0; i < games; i++ {
+			select {
+			case seeds <- firstSeed + int64(i):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	stats := newRunStats()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for seed := range seeds {
+				var gameLog *bytes.Buffer
+				var boardLog io.Writer
+				if r.boardLog != nil {
+					gameLog = &bytes.Buffer{}
+					boardLog = gameLog
+				}
+				result, recording, err := playNewGame(ctx, r.backend, r.strategy, seed, playOptions{
+					boardLog:  boardLog,
+					record:    r.recordDir != "",
+					flagMines: r.flagMines,
+					chord:     r.chord,
+					board:     r.board,
+					opening:   r.opening,
+				})
+				if err != nil && ctx.Err() != nil {
+					result.Status = statusAborted
+				} else if err != nil {
+					result.Status = statusError
+					result.Error = err.Error()
+					result.ErrorKind = errorServer
+					if gameErr, ok := err.(gameError); ok {
+						result.ErrorKind = gameErr.Kind
+					}
+				}
+				if recording != nil {
+					if err := recording.save(r.recordDir); err != nil {
+						fmt.Fprintf(os.Stderr, "can't save recording of game %s: %v\n", result.GameId, err)
+					}
+				}
+
+				mu.Lock()
+				if gameLog != nil {
+					_, _ = gameLog.WriteTo(r.boardLog)
+				}
+				stats.add(result)
+				if onResult != nil {
+					onResult(result, stats)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return stats
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"minesweeper-bot/swagger"
+	"net/http"
+	"os"
+	"time"
+)
+
+// maxHintRequestSize caps the body of a hint request. The biggest boards anyone plays are far
+// below it.
+const maxHintRequestSize = 1 << 20
+
+// maxHintCells caps the size of the boards hints are given for, over three times an expert
+// board. The solver only estimates frontiers too long to enumerate, but the work it still does
+// grows faster than the board, and a request shouldn't take more than a moment.
+const maxHintCells = 40 * 40
+
+func serveCommand(args []string) error {
+	flags := newFlagSet("serve")
+	listen := flags.String("listen", "localhost:8080", "address to listen on")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hint", hintHandler)
+	server := &http.Server{
+		Addr:         *listen,
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	ctx, stop := interruptibleContext("waiting up to 5s for the requests in progress")
+	defer stop()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+	fmt.Fprintf(os.Stderr, "serving hints on http://%s/hint\n", *listen)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	}
+}
+
+// hintCell is a cell in hint responses. MineProbability is left out of the safe and mine lists,
+// where it goes without saying.
+type hintCell struct {
+	X               int      `json:"x"`
+	Y               int      `json:"y"`
+	MineProbability *float64 `json:"mine_probability,omitempty"`
+}
+
+type hintResponse struct {
+	Safe          []hintCell `json:"safe"`
+	Mines         []hintCell `json:"mines"`
+	Probabilities []hintCell `json:"probabilities"` // every unknown cell, in reading order
+	Recommended   *hintCell  `json:"recommended,omitempty"`
+}
+
+type hintError struct {
+	Error string `json:"error"`
+}
+
+// hintHandler takes a board shaped like swagger.Game, e.g.
+//
+//	{"board_width": 3, "board_height": 2, "mines_count": 1, "board_state": ["1","?","?","1","?","?"]}
+//
+// and answers with a hintResponse.
+func hintHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, hintError{"POST a board to get hints for it"})
+		return
+	}
+	var game swagger.Game
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxHintRequestSize))
+	if err := decoder.Decode(&game); err != nil {
+		writeJSON(w, http.StatusBadRequest, hintError{fmt.Sprintf("can't read board: %v", err)})
+		return
+	}
+	if cells := int(game.BoardWidth) * int(game.BoardHeight); cells > maxHintCells {
+		writeJSON(w, http.StatusRequestEntityTooLarge, hintError{fmt.Sprintf("boards of more than %d cells aren't supported, got %dx%d", maxHintCells, game.BoardWidth, game.BoardHeight)})
+		return
+	}
+	analysis, err := analyzeBoard(game)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, hintError{err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, newHintResponse(analysis))
+}
+
+func newHintResponse(analysis boardAnalysis) hintResponse {
+	response := hintResponse{
+		Safe:          make([]hintCell, 0, len(analysis.Safe)),
+		Mines:         make([]hintCell, 0, len(analysis.Mines)),
+		Probabilities: make([]hintCell, 0, len(analysis.Probabilities)),
+	}
+	for _, loc := range analysis.Safe {
+		response.Safe = append(response.Safe, hintCell{X: loc.X, Y: loc.Y})
+	}
+	for _, loc := range analysis.Mines {
+		response.Mines = append(response.Mines, hintCell{X: loc.X, Y: loc.Y})
+	}
+	unknowns := make([]location, 0, len(analysis.Probabilities))
+	for loc := range analysis.Probabilities {
+		unknowns = append(unknowns, loc)
+	}
+	sortLocations(unknowns)
+	for _, loc := range unknowns {
+		probability := analysis.Probabilities[loc]
+		response.Probabilities = append(response.Probabilities, hintCell{X: loc.X, Y: loc.Y, MineProbability: &probability})
+	}
+	if analysis.Recommended != nil {
+		risk := analysis.Recommended.Risk
+		response.Recommended = &hintCell{X: analysis.Recommended.Cell.X, Y: analysis.Recommended.Cell.Y, MineProbability: &risk}
+	}
+	return response
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("can't write response: %v", err)
+	}
+}
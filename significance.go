@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"minesweeper-bot/engine"
+	statistics "minesweeper-bot/stats"
+	"os"
+	"time"
+)
+
+func statsCommand(args []string) error {
+	flags := newFlagSet("stats")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s stats <run.jsonl> [<other-run.jsonl>]\n", programName())
+		fmt.Fprintln(flags.Output(), "Summarizes a run written by `play -format jsonl`. Given two runs, tests whether the second one is better.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		return usageError{"stats needs one or two run files"}
+	}
+
+	runs := make([][]gameResult, 0, flags.NArg())
+	for _, path := range flags.Args() {
+		results, err := loadRunResults(path)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, results)
+
+		fmt.Printf("== %s\n", path)
+		stats := newRunStats()
+		for _, result := range results {
+			stats.add(result)
+		}
+		if err := (textReporter{out: os.Stdout, summaryOut: os.Stdout}).runFinished(stats); err != nil {
+			return err
+		}
+	}
+
+	if len(runs) == 2 {
+		fmt.Printf("== %s vs %s\n", flags.Arg(1), flags.Arg(0))
+		printComparison(os.Stdout, flags.Arg(0), runs[0], flags.Arg(1), runs[1])
+	}
+	return nil
+}
+
+// loadRunResults reads the games of a JSON Lines report, skipping its summary.
+func loadRunResults(path string) ([]gameResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	results := make([]gameResult, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024) // final boards make for long lines
+	for line := 1; scanner.Scan(); line++ {
+		var record gameRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		if record.Record == "game" {
+			results = append(results, record.result())
+		}
+	}
+	return results, scanner.Err()
+}
+
+func (record gameRecord) result() gameResult {
+	return gameResult{
+		GameId:     record.GameId,
+		Seed:       record.Seed,
+		BoardSeed:  record.BoardSeed,
+		FirstClick: engine.FirstClick(record.FirstClick),
+		Status:     record.Status,
+		MinesFound: record.MinesFound,
+		MinesTotal: record.MinesTotal,
+		Moves:      record.Moves,
+		Guesses:    record.Guesses,
+		Duration:   time.Duration(record.DurationMs * float64(time.Millisecond)),
+		FinalBoard: record.FinalBoard,
+		ErrorKind:  gameErrorKind(record.ErrorKind),
+		Error:      record.Error,
+	}
+}
+
+// printComparison tests whether run B won more often than run A. Games with the same seed in
+// both runs are also compared pairwise, which needs far fewer games to tell a difference, as
+// long as they really were played on the same boards, see samePlayedBoard.
+func printComparison(w io.Writer, nameA string, a []gameResult, nameB string, b []gameResult) {
+	statsA, statsB := newRunStats(), newRunStats()
+	for _, result := range a {
+		statsA.add(result)
+	}
+	for _, result := range b {
+		statsB.add(result)
+	}
+	rateA, _ := statsA.winRate()
+	rateB, _ := statsB.winRate()
+	test := statistics.TwoProportion(statsA.results["win"], statsA.finishedGames(), statsB.results["win"], statsB.finishedGames())
+	_, _ = fmt.Fprintf(w, "win rate %.1f%% for %s vs %.1f%% for %s (%+.1f points), two-proportion z = %.2f, p = %.4f\n",
+		rateB*100, nameB, rateA*100, nameA, (rateB-rateA)*100, test.Z, test.PValue)
+
+	bySeed := make(map[int64]gameResult)
+	for _, result := range a {
+		if isFinished(result) {
+			bySeed[result.Seed] = result
+		}
+	}
+	paired, unpaired, onlyA, onlyB := 0, 0, 0, 0
+	for _, resultB := range b {
+		resultA, ok := bySeed[resultB.Seed]
+		if !ok || !isFinished(resultB) {
+			continue
+		}
+		if !samePlayedBoard(resultA, resultB) {
+			unpaired++
+			continue
+		}
+		paired++
+		wonA, wonB := resultA.Status == "win", resultB.Status == "win"
+		if wonA && !wonB {
+			onlyA++
+		} else if wonB && !wonA {
+			onlyB++
+		}
+	}
+	if unpaired > 0 {
+		_, _ = fmt.Fprintf(w, "%d games share a seed but not a board (the backend didn't make it from the seed, or the first click rules differ), can't compare game by game\n", unpaired)
+		return
+	}
+	if paired == 0 {
+		_, _ = fmt.Fprintln(w, "no seeds in common, can't compare game by game")
+		return
+	}
+	pairedTest := statistics.McNemar(onlyA, onlyB)
+	_, _ = fmt.Fprintf(w, "on %d common seeds: %d won only by %s, %d only by %s, McNemar p = %.4f\n",
+		paired, onlyB, nameB, onlyA, nameA, pairedTest.PValue)
+	_, _ = fmt.Fprintf(w, "p is the chance of %s looking at least this much better if it were no better than %s\n", nameB, nameA)
+}
+
+// samePlayedBoard tells whether two games of the same seed were played on the same board: the backend
+// made both boards from the seed, under the same first click rule, with as many mines. Runs
+// written before board seeds were recorded never pair up.
+func samePlayedBoard(a, b gameResult) bool {
+	return a.BoardSeed == a.Seed && b.BoardSeed == b.Seed && a.FirstClick == b.FirstClick && a.MinesTotal == b.MinesTotal
+}
+
+// isFinished tells if a game got to an end, as opposed to being errored or aborted.
+func isFinished(result gameResult) bool {
+	return result.Status != statusError && result.Status != statusAborted
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"minesweeper-bot/engine"
+	"testing"
+)
+
+func TestSamePlayedBoard(t *testing.T) {
+	game := gameResult{Seed: 5, BoardSeed: 5, FirstClick: engine.FirstClickCentre, MinesTotal: 40}
+	tests := []struct {
+		name  string
+		other gameResult
+		want  bool
+	}{
+		{"same board", game, true},
+		{"no board seed", gameResult{Seed: 5, FirstClick: engine.FirstClickCentre, MinesTotal: 40}, false},
+		{"other board seed", gameResult{Seed: 5, BoardSeed: 9, FirstClick: engine.FirstClickCentre, MinesTotal: 40}, false},
+		{"other first click rule", gameResult{Seed: 5, BoardSeed: 5, FirstClick: engine.FirstClickZero, MinesTotal: 40}, false},
+		{"other number of mines", gameResult{Seed: 5, BoardSeed: 5, FirstClick: engine.FirstClickCentre, MinesTotal: 99}, false},
+	}
+	for _, tt := range tests {
+		if got := samePlayedBoard(game, tt.other); got != tt.want {
+			t.Errorf("%s: samePlayedBoard = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+func solveCommand(args []string) error {
+	flags := newFlagSet("solve")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s solve [flags] [board.txt]\n", programName())
+		fmt.Fprintln(flags.Output(), "Reads the board from stdin when no file is given. See parseBoard for the format.")
+		flags.PrintDefaults()
+	}
+	mines := flags.Int("mines", -1, "total number of mines, overrides the board's \"mines:\" line")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return usageError{"solve takes at most one board file"}
+	}
+
+	var in io.Reader = os.Stdin
+	if flags.NArg() == 1 {
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	game, err := parseBoard(in)
+	if err != nil {
+		return err
+	}
+	if *mines >= 0 {
+		game.MinesCount = int32(*mines)
+	}
+	analysis, err := analyzeBoard(game)
+	if err != nil {
+		return err
+	}
+	printAnalysis(os.Stdout, newGameInfo(game), analysis)
+	return nil
+}
+
+// printAnalysis draws the board with what the solver found on it: "s" for safe cells, "m" for
+// mines, the chance of a mine in tens of percent for the rest, and the recommended move
+// highlighted. The findings are listed under it.
+func printAnalysis(w io.Writer, game gameInformation, analysis boardAnalysis) {
+	safe := make(map[location]bool)
+	for _, loc := range analysis.Safe {
+		safe[loc] = true
+	}
+	printAnnotatedBoardState(w, game, func(loc location, cellState string) string {
+		text := colored(cellState)
+		if probability, ok := analysis.Probabilities[loc]; ok && cellState == "?" {
+			text = probabilityCell(probability, safe[loc])
+		}
+		if analysis.Recommended != nil && loc == analysis.Recommended.Cell {
+			text = fmt.Sprintf("\u001b[7m%s\u001b[0m", cellState)
+		}
+		return text
+	})
+
+	if game.MinesCount > 0 {
+		_, _ = fmt.Fprintf(w, "%d mines, %d not found yet\n", game.MinesCount, game.minesLeft()-len(analysis.Mines))
+	} else {
+		_, _ = fmt.Fprintln(w, "mine count unknown, probabilities only follow from the numbers")
+	}
+	_, _ = fmt.Fprintf(w, "safe: %s\n", formatLocations(analysis.Safe))
+	_, _ = fmt.Fprintf(w, "mines: %s\n", formatLocations(analysis.Mines))
+	switch {
+	case analysis.Recommended == nil && game.grid.unknown.count() > 0:
+		_, _ = fmt.Fprintln(w, "no move to recommend: no number touches the unknown cells and the mine count is unknown")
+	case analysis.Recommended == nil:
+		_, _ = fmt.Fprintln(w, "nothing left to open")
+	case analysis.Recommended.Guess:
+		_, _ = fmt.Fprintf(w, "recommended: guess (%d, %d), %.1f%% chance of a mine\n",
+			analysis.Recommended.Cell.X, analysis.Recommended.Cell.Y, analysis.Recommended.Risk*100)
+	default:
+		_, _ = fmt.Fprintf(w, "recommended: open (%d, %d), it's safe\n", analysis.Recommended.Cell.X, analysis.Recommended.Cell.Y)
+	}
+}
+
+func formatLocations(locs []location) string {
+	if len(locs) == 0 {
+		return "none"
+	}
+	parts := make([]string, 0, len(locs))
+	for _, loc := range locs {
+		parts = append(parts, fmt.Sprintf("(%d, %d)", loc.X, loc.Y))
+	}
+	return strings.Join(parts, " ")
+}
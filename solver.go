@@ -2,202 +2,248 @@ package main
 
 import (
 	"fmt"
+	"minesweeper-bot/engine"
 	"minesweeper-bot/swagger"
-	"strconv"
+	"strings"
 )
 
+// flaggedCell is how the server shows a flagged cell.
+const flaggedCell = "F"
+
 type location struct {
 	X, Y int
 }
 
+// less orders locations top to bottom, left to right. It's used to break ties, so that the same
+// board always gets the same moves.
+func (loc location) less(other location) bool {
+	if loc.Y != other.Y {
+		return loc.Y < other.Y
+	}
+	return loc.X < other.X
+}
+
 type gameInformation struct {
 	*swagger.Game
-	cellsToOpen   []location
-	bombLocations map[location]bool
-
-	fullyRevealedLocations map[location]bool
+	// grid is the board the solver works on, with every bomb found so far marked on it. It's
+	// rebuilt from BoardState whenever the server sends a new board, see receive. BoardState
+	// gets the bombs marked as "*" too, for printing.
+	grid grid
+
+	// cellsToOpen keeps the order the safe cells were found in, queued is the same cells as a set
+	cellsToOpen []location
+	queued      bitset
+	bombs       bitset
+	// flagged are the cells flagged on the server's board. They're bombs to the solver, so the
+	// board shows them as "*" like every other bomb.
+	flagged bitset
+
+	// fullyRevealed are the cells with no unknown cells around, which have nothing left to say
+	fullyRevealed bitset
+	// frontier are the numbers that still have unknown cells around. The passes that need every
+	// number with something left to say go over these instead of the whole board.
+	frontier bitset
+	// dirty are the cells that changed, or had a neighbour change, since the single-cell rules
+	// last looked at them. Those rules only ever look at dirty cells, so what they cost after a
+	// move depends on how much of the board the move changed, not on the size of the board.
+	dirty bitset
+
+	// seed the game was requested with, kept so that a lost game can be replayed
+	seed int64
+	// boardSeed is the seed the backend says the board was made with, 0 if it didn't say
+	boardSeed int64
+	// firstClick is the backend's rule for the first cell opened
+	firstClick engine.FirstClick
+
+	movesMade    int
+	guessesTaken int
 
 	verbose bool
 }
 
 func newGameInfo(game swagger.Game) gameInformation {
-	return gameInformation{
-		Game:                   &game,
-		cellsToOpen:            make([]location, 0),
-		bombLocations:          make(map[location]bool),
-		fullyRevealedLocations: make(map[location]bool),
+	cells := len(game.BoardState)
+	gameInfo := gameInformation{
+		Game:          &game,
+		grid:          newGrid(&game),
+		cellsToOpen:   make([]location, 0),
+		queued:        newBitset(cells),
+		bombs:         newBitset(cells),
+		flagged:       newBitset(cells),
+		fullyRevealed: newBitset(cells),
+		frontier:      newBitset(cells),
+		dirty:         newBitset(cells),
 	}
+	gameInfo.addFlags()
+	for offset := 0; offset < cells; offset++ {
+		gameInfo.dirty.add(offset)
+	}
+	return gameInfo
 }
 
-func (game *gameInformation) queueCellToOpen(cell location) {
-	for _, loc := range game.cellsToOpen {
-		if cell == loc {
-			return
+// receive takes a new board from the server. Unless the game is over, every bomb found so far is
+// marked on it again, the cells flagged on it are taken as bombs, and the cells that differ from
+// the previous board are marked dirty.
+func (game *gameInformation) receive(state swagger.Game) {
+	previous := game.grid
+	*game.Game = state
+	game.grid = newGrid(game.Game)
+	if game.IsFinished() {
+		// the server shows every mine now, ours would only hide the ones we got wrong
+		return
+	}
+	game.addFlags()
+	for _, offset := range game.bombs.members() {
+		game.grid.markBomb(offset)
+		game.BoardState[offset] = "*"
+	}
+	for offset, value := range game.grid.cells {
+		if value != previous.cells[offset] {
+			game.markDirty(offset)
 		}
 	}
-
-	game.cellsToOpen = append(game.cellsToOpen, cell)
 }
 
-func (game *gameInformation) addFullyRevealedLocations() {
-	for offset := range game.BoardState {
-		y := offset / int(game.BoardWidth)
-		x := offset - y*int(game.BoardWidth)
-
-		loc := location{x, y}
-		if game.fullyRevealedLocations[loc] {
-			continue
-		}
-
-		unknownLocs := game.findUnknownCellsAround(x, y)
-		if len(unknownLocs) == 0 {
-			game.fullyRevealedLocations[loc] = true
+func (game *gameInformation) addFlags() {
+	for offset, cellState := range game.BoardState {
+		if cellState == flaggedCell {
+			game.flagged.add(offset)
+			game.bombs.add(offset)
 		}
 	}
 }
 
-func (game *gameInformation) refreshBombs() {
-	newBombLocs := game.markNewBombs()
-	for len(newBombLocs) > 0 {
-		game.applyBombLocations(game.bombLocations)
-		for _, loc := range newBombLocs {
-			if !game.bombLocations[loc] {
-				game.bombLocations[loc] = true
-			}
-		}
-		game.applyBombLocations(game.bombLocations)
-		newBombLocs = game.markNewBombs()
+// markBomb records a cell that's certain to contain a bomb and marks it on the board.
+func (game *gameInformation) markBomb(offset int) {
+	if game.grid.cells[offset] == gridBomb {
+		return
 	}
+	game.bombs.add(offset)
+	game.grid.markBomb(offset)
+	game.BoardState[offset] = "*"
+	game.markDirty(offset)
 }
 
-func (game *gameInformation) markNewBombs() []location {
-	result := make([]location, 0)
-	for offset, cell := range game.BoardState {
-		count, err := strconv.Atoi(cell)
-		if err != nil {
-			continue
-		}
-		y := offset / int(game.BoardWidth)
-		x := offset - y*int(game.BoardWidth)
-		bombLocs := game.findBombsAround(x, y)
-		if len(bombLocs) == count {
-			continue
-		}
-
-		locs := game.findUnknownCellsAround(x, y)
-
-		if len(locs)+len(bombLocs) == count {
-			result = locs
-			break
-		}
+// markDirty tells the single-cell rules that a cell changed, so it and its neighbours need
+// another look.
+func (game *gameInformation) markDirty(offset int) {
+	game.dirty.add(offset)
+	for _, n := range game.grid.neighbours[offset] {
+		game.dirty.add(n)
 	}
-	return result
 }
 
-func (game *gameInformation) findUnknownCellsAround(x int, y int) []location {
-	return game.findCellsAround(x, y, "?")
+func (game *gameInformation) queueCellToOpen(offset int) {
+	if game.queued.has(offset) {
+		return
+	}
+
+	game.queued.add(offset)
+	game.cellsToOpen = append(game.cellsToOpen, game.grid.location(offset))
 }
 
-func (game *gameInformation) findBombsAround(x int, y int) []location {
-	return game.findCellsAround(x, y, "*")
+// isUnknown tells if a cell is on the board and neither open nor known to hold a bomb.
+func (game *gameInformation) isUnknown(loc location) bool {
+	return game.grid.contains(loc) && game.grid.cells[game.grid.offset(loc)] == gridUnknown
 }
 
-func (game *gameInformation) findCellsAround(x int, y int, marker string) []location {
-	result := make([]location, 0)
-	for i := x - 1; i <= x+1; i++ {
-		for j := y - 1; j <= y+1; j++ {
-			if i == x && j == y || i < 0 || j < 0 || i >= int(game.BoardWidth) || j >= int(game.BoardHeight) {
-				continue
-			}
-			if game.fetchCell(i, j) == marker {
-				result = append(result, location{X: i, Y: j})
-			}
+// addFullyRevealedLocations looks at the dirty cells for ones that got fully revealed, and keeps
+// the frontier up to date.
+func (game *gameInformation) addFullyRevealedLocations() {
+	for _, offset := range game.dirty.members() {
+		if game.fullyRevealed.has(offset) {
+			continue
+		}
+		if game.grid.countAround(offset, gridUnknown) == 0 {
+			game.fullyRevealed.add(offset)
+			game.frontier.remove(offset)
+		} else if _, ok := game.grid.number(offset); ok {
+			game.frontier.add(offset)
 		}
 	}
-	return result
-}
-
-func (game *gameInformation) fetchCell(x, y int) string {
-	offset := y*int(game.BoardWidth) + x
-	return game.BoardState[offset]
 }
 
-func (game *gameInformation) applyBombLocations(bombLocations map[location]bool) {
-	for loc := range bombLocations {
-		offset := loc.Y*int(game.BoardWidth) + loc.X
-		game.BoardState[offset] = "*"
+// refreshBombs marks bombs until no dirty number has as many unknown cells around as it lacks
+// bombs. Every bomb marked makes its neighbours dirty, so they get checked on the next round.
+func (game *gameInformation) refreshBombs() {
+	newBombLocs := game.markNewBombs()
+	for len(newBombLocs) > 0 {
+		for _, offset := range newBombLocs {
+			game.markBomb(offset)
+		}
+		newBombLocs = game.markNewBombs()
 	}
 }
 
-// if we got to this point, then multiple cells can contain a bomb. Some more likely than others.
-// findLeastRiskyCells evaluates/intersects area of effect of numbered cells and tries to guess which
-// cells are more likely to contain a bomb. And, as a consequence, we get the "least likely" cells.
-func (game *gameInformation) findLeastRiskyCell() (location, error) {
-	probabilitiesOfBomb := make(map[location]float64)
-
-	for offset, cellState := range game.BoardState {
-		y := offset / int(game.BoardWidth)
-		x := offset - y*int(game.BoardWidth)
-
-		if game.fullyRevealedLocations[location{x, y}] {
+// markNewBombs finds the dirty numbers with as many unknown cells around as they lack bombs, and
+// returns those cells. Marking some of them doesn't change what the others need, since every
+// bomb marked is one unknown cell less, so they can all be marked at once.
+func (game *gameInformation) markNewBombs() []int {
+	g := &game.grid
+	var result []int
+	for _, offset := range game.dirty.members() {
+		count, ok := g.number(offset)
+		if !ok {
 			continue
 		}
-
-		count, err := strconv.Atoi(cellState)
-		if err != nil { // not a numbered cell
+		bombs := g.countAround(offset, gridBomb)
+		if bombs == count {
 			continue
 		}
 
-		visibleBombs := game.findBombsAround(x, y)
-		unknowns := game.findUnknownCellsAround(x, y)
-		for _, loc := range unknowns {
-			additionalRisk := float64(count-len(visibleBombs)) / float64(len(unknowns))
-			probabilitiesOfBomb[loc] += additionalRisk
+		unknowns := g.countAround(offset, gridUnknown)
+		if unknowns+bombs == count {
+			result = g.appendAround(result, offset, gridUnknown)
 		}
 	}
+	return result
+}
 
-	// find loc with lowest probability
-	var leastRiskyLoc location
+// if we got to this point, then multiple cells can contain a bomb. Some more likely than others.
+// findLeastRiskyCell asks mineProbabilities for the exact chance of a bomb in every unknown cell
+// and returns the one least likely to blow up, along with that chance.
+func (game *gameInformation) findLeastRiskyCell() (location, float64, error) {
+	probabilitiesOfBomb := game.mineProbabilities()
+
+	// find the cell with the lowest probability. Cells are visited in the order of location.less,
+	// so ties go to the first one.
+	leastRiskyCell := -1
 	var leastRisk float64
-	found := false
-
-	for loc, risk := range probabilitiesOfBomb {
-		if !found {
-			leastRiskyLoc = loc
+	for offset, risk := range probabilitiesOfBomb {
+		if risk == noProbability {
+			continue
+		}
+		if leastRiskyCell < 0 || risk < leastRisk {
+			leastRiskyCell = offset
 			leastRisk = risk
-			found = true
-		} else {
-			if risk < leastRisk {
-				leastRisk = risk
-				leastRiskyLoc = loc
-			}
 		}
 	}
-	if found {
-		return leastRiskyLoc, nil
+	if leastRiskyCell >= 0 {
+		return game.grid.location(leastRiskyCell), leastRisk, nil
 	}
-	return location{}, fmt.Errorf("can't find least risky cell")
+	return location{}, 0, fmt.Errorf("can't find least risky cell")
 }
 
+// findSafeCells queues the unknown cells around dirty numbers that already see all their bombs.
+// It's the last of the single-cell rules, so it leaves no cell dirty, after checking whether any
+// of them got fully revealed, as nothing else would look at them again.
 func (game *gameInformation) findSafeCells() {
-	for offset, cellState := range game.BoardState {
-		y := offset / int(game.BoardWidth)
-		x := offset - y*int(game.BoardWidth)
-
-		if game.fullyRevealedLocations[location{x, y}] {
+	g := &game.grid
+	game.addFullyRevealedLocations()
+	defer game.dirty.clear()
+	for _, offset := range game.dirty.members() {
+		if game.fullyRevealed.has(offset) {
 			continue
 		}
-		count, err := strconv.Atoi(cellState)
-		if err != nil {
+		count, ok := g.number(offset)
+		if !ok {
 			continue
 		}
 
-		bombLocs := game.findBombsAround(x, y)
-		if len(bombLocs) == count { // cell at (x,y) already sees all its bombs. It's safe to open all unknowns
-			unknownLocs := game.findUnknownCellsAround(x, y)
-			if len(unknownLocs) > 0 {
-				for _, loc := range unknownLocs {
-					game.queueCellToOpen(loc)
+		if g.countAround(offset, gridBomb) == count { // cell already sees all its bombs. It's safe to open all unknowns
+			for _, n := range g.neighbours[offset] {
+				if g.cells[n] == gridUnknown {
+					game.queueCellToOpen(n)
 				}
 			}
 		}
@@ -210,10 +256,30 @@ func (game *gameInformation) IsFinished() bool {
 
 func (game *gameInformation) Result() gameResult {
 	return gameResult{
+		GameId:     game.GameId,
+		Seed:       game.seed,
+		BoardSeed:  game.boardSeed,
+		FirstClick: game.firstClick,
 		Status:     game.Status,
 		MinesFound: game.NumberOfCorrectlyGuessedBombs(),
 		MinesTotal: int(game.MinesCount),
+		Moves:      game.movesMade,
+		Guesses:    game.guessesTaken,
+		FinalBoard: boardString(game.Game),
+	}
+}
+
+// boardString writes a board on one line: the cells of each row as they are in BoardState,
+// rows separated by "/". E.g. "1?/*1" is a 2x2 board.
+func boardString(game *swagger.Game) string {
+	var sb strings.Builder
+	for offset, cellState := range game.BoardState {
+		if offset > 0 && offset%int(game.BoardWidth) == 0 {
+			sb.WriteString("/")
+		}
+		sb.WriteString(cellState)
 	}
+	return sb.String()
 }
 
 func (game *gameInformation) NumberOfCorrectlyGuessedBombs() int {
@@ -222,8 +288,8 @@ func (game *gameInformation) NumberOfCorrectlyGuessedBombs() int {
 	}
 
 	found := 0
-	for loc := range game.bombLocations {
-		if game.fetchCell(loc.X, loc.Y) == "*" {
+	for _, offset := range game.bombs.members() {
+		if game.BoardState[offset] == "*" {
 			found++
 		}
 	}
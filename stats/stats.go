@@ -0,0 +1,152 @@
+// Package stats has the statistics the bot reports about a run: confidence intervals for win
+// rates, summaries of per-game numbers and tests for whether one solver beats another.
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Z95 is the z-score of a two-sided 95% confidence level.
+const Z95 = 1.959963984540054
+
+// Interval is a confidence interval.
+type Interval struct {
+	Low, High float64
+}
+
+// Wilson returns the Wilson score interval of a proportion with the given z-score, e.g. Z95.
+// Unlike the textbook p ± z·sqrt(p(1-p)/n), it stays within [0, 1] and works for rates close
+// to 0 or 1 and for small samples.
+func Wilson(successes, trials int, z float64) Interval {
+	if trials == 0 {
+		return Interval{Low: 0, High: 1}
+	}
+	n := float64(trials)
+	p := float64(successes) / n
+	z2 := z * z
+	centre := (p + z2/(2*n)) / (1 + z2/n)
+	halfWidth := z / (1 + z2/n) * math.Sqrt(p*(1-p)/n+z2/(4*n*n))
+	return Interval{
+		Low:  math.Max(0, centre-halfWidth),
+		High: math.Min(1, centre+halfWidth),
+	}
+}
+
+// Summary describes a sample of numbers.
+type Summary struct {
+	Count  int
+	Mean   float64
+	Median float64
+	StdDev float64 // sample standard deviation
+	Min    float64
+	Max    float64
+}
+
+// Describe summarizes values. The zero Summary is returned for an empty sample.
+func Describe(values []float64) Summary {
+	if len(values) == 0 {
+		return Summary{}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	mean := sum / float64(len(sorted))
+
+	squares := 0.0
+	for _, v := range sorted {
+		squares += (v - mean) * (v - mean)
+	}
+	stdDev := 0.0
+	if len(sorted) > 1 {
+		stdDev = math.Sqrt(squares / float64(len(sorted)-1))
+	}
+
+	return Summary{
+		Count:  len(sorted),
+		Mean:   mean,
+		Median: median(sorted),
+		StdDev: stdDev,
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+	}
+}
+
+func median(sorted []float64) float64 {
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[middle]
+	}
+	return (sorted[middle-1] + sorted[middle]) / 2
+}
+
+// Histogram counts how many times each value occurs.
+func Histogram(values []int) map[int]int {
+	result := make(map[int]int)
+	for _, v := range values {
+		result[v]++
+	}
+	return result
+}
+
+// TestResult is the outcome of a significance test.
+type TestResult struct {
+	// Z is the test statistic; it's positive when B did better than A.
+	Z float64
+	// PValue is the one-sided probability of B doing at least this much better than A if both
+	// were equally good. Small values (say below 0.05) mean B really is better.
+	PValue float64
+}
+
+// TwoProportion compares two independent samples, e.g. win counts of two solvers that played
+// different boards, with the pooled two-proportion z-test.
+func TwoProportion(successesA, trialsA, successesB, trialsB int) TestResult {
+	if trialsA == 0 || trialsB == 0 {
+		return TestResult{PValue: 1}
+	}
+	nA, nB := float64(trialsA), float64(trialsB)
+	pA, pB := float64(successesA)/nA, float64(successesB)/nB
+	pooled := float64(successesA+successesB) / (nA + nB)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/nA + 1/nB))
+	if se == 0 {
+		return TestResult{PValue: 1}
+	}
+	z := (pB - pA) / se
+	return TestResult{Z: z, PValue: upperTail(z)}
+}
+
+// McNemar compares two solvers that played the same boards. Only the boards where they
+// disagreed count: onlyA is the number of boards won by A alone, onlyB by B alone. This is more
+// sensitive than TwoProportion on paired results, since the luck of the boards cancels out.
+func McNemar(onlyA, onlyB int) TestResult {
+	discordant := onlyA + onlyB
+	if discordant == 0 {
+		return TestResult{PValue: 1}
+	}
+	z := float64(onlyB-onlyA) / math.Sqrt(float64(discordant))
+
+	// exact binomial test: chance of B winning at least onlyB of the discordant boards if
+	// each of them was a coin flip
+	logHalf := float64(discordant) * math.Log(0.5)
+	pValue := 0.0
+	for k := onlyB; k <= discordant; k++ {
+		pValue += math.Exp(logBinomial(discordant, k) + logHalf)
+	}
+	return TestResult{Z: z, PValue: math.Min(1, pValue)}
+}
+
+func logBinomial(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
+
+// upperTail is P(Z >= z) for a standard normal Z.
+func upperTail(z float64) float64 {
+	return 0.5 * math.Erfc(z/math.Sqrt2)
+}
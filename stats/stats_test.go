@@ -0,0 +1,51 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-4
+}
+
+func TestWilson(t *testing.T) {
+	tests := []struct {
+		successes, trials int
+		want              Interval
+	}{
+		{8, 10, Interval{0.4902, 0.9433}},
+		{50, 100, Interval{0.4038, 0.5962}},
+		{0, 10, Interval{0, 0.2775}},
+		{10, 10, Interval{0.7225, 1}},
+		{0, 0, Interval{0, 1}},
+	}
+	for _, tt := range tests {
+		got := Wilson(tt.successes, tt.trials, Z95)
+		if !near(got.Low, tt.want.Low) || !near(got.High, tt.want.High) {
+			t.Errorf("Wilson(%d, %d) = [%.4f, %.4f], want [%.4f, %.4f]",
+				tt.successes, tt.trials, got.Low, got.High, tt.want.Low, tt.want.High)
+		}
+	}
+}
+
+func TestMcNemar(t *testing.T) {
+	tests := []struct {
+		onlyA, onlyB int
+		want         TestResult
+	}{
+		// P(X >= 8) for X ~ Binomial(10, 1/2) is 56/1024
+		{2, 8, TestResult{Z: 6 / math.Sqrt(10), PValue: 56.0 / 1024}},
+		{5, 5, TestResult{Z: 0, PValue: 638.0 / 1024}},
+		{8, 2, TestResult{Z: -6 / math.Sqrt(10), PValue: 1013.0 / 1024}},
+		{0, 3, TestResult{Z: math.Sqrt(3), PValue: 1.0 / 8}},
+		{0, 0, TestResult{Z: 0, PValue: 1}},
+	}
+	for _, tt := range tests {
+		got := McNemar(tt.onlyA, tt.onlyB)
+		if !near(got.Z, tt.want.Z) || !near(got.PValue, tt.want.PValue) {
+			t.Errorf("McNemar(%d, %d) = z %.4f p %.4f, want z %.4f p %.4f",
+				tt.onlyA, tt.onlyB, got.Z, got.PValue, tt.want.Z, tt.want.PValue)
+		}
+	}
+}
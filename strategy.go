@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Strategy decides which cells to open next. It's given the bot's view of the board: the latest
+// server response with every bomb found so far marked on it. Strategies keep their per-game
+// bookkeeping in gameInformation, so a single Strategy value can play any number of games.
+type Strategy interface {
+	NextMoves(game *gameInformation) ([]plannedMove, error)
+}
+
+// plannedMove is a cell a strategy wants opened, or another move the bot means to send.
+type plannedMove struct {
+	Cell location
+	// Action is one of the swagger.MoveAction values, empty to open the cell.
+	Action string
+	// Guess is set when the strategy isn't sure the cell is safe.
+	Guess bool
+	// Risk is the strategy's estimate of the chance that a guessed cell holds a bomb.
+	Risk float64
+}
+
+// strategies are the solvers the runner can pick from by name.
+var strategies = map[string]Strategy{
+	"baseline": baselineStrategy{},
+	"pairs":    pairsStrategy{},
+	"full":     fullStrategy{},
+	"informed": informedStrategy{},
+}
+
+const defaultStrategyName = "full"
+
+func strategyByName(name string) (Strategy, error) {
+	strategy, ok := strategies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy %q, known strategies: %v", name, strategyNames())
+	}
+	return strategy, nil
+}
+
+func strategyNames() []string {
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// baselineStrategy is the original bot: only the two single-cell rules, and when they're stuck,
+// a guess based on adding up the risk every neighbouring number spreads over its unknown cells.
+type baselineStrategy struct{}
+
+func (baselineStrategy) NextMoves(game *gameInformation) ([]plannedMove, error) {
+	game.addFullyRevealedLocations()
+	game.refreshBombs()
+	game.findSafeCells()
+	if len(game.cellsToOpen) > 0 {
+		return game.takeQueuedCells(), nil
+	}
+
+	loc, risk, err := game.findLeastRiskyCellByAddingRisks()
+	if err != nil {
+		return nil, err
+	}
+	return []plannedMove{{Cell: loc, Guess: true, Risk: risk}}, nil
+}
+
+// pairsStrategy adds the constraint pair rules to the baseline and guesses by exact probability.
+type pairsStrategy struct{}
+
+func (pairsStrategy) NextMoves(game *gameInformation) ([]plannedMove, error) {
+	game.addFullyRevealedLocations()
+	game.deduceUntilStuck(false)
+	return game.nextMovesOrGuess()
+}
+
+// fullStrategy uses every deduction the solver knows, the global mine count included, before
+// guessing by exact probability.
+type fullStrategy struct{}
+
+func (fullStrategy) NextMoves(game *gameInformation) ([]plannedMove, error) {
+	game.addFullyRevealedLocations()
+	game.deduceUntilStuck(true)
+	return game.nextMovesOrGuess()
+}
+
+// informedStrategy deduces like the full strategy, but when it has to guess it weighs each
+// cell's risk against the chance that opening it gets the solver going again, see findBestGuess.
+type informedStrategy struct{}
+
+func (informedStrategy) NextMoves(game *gameInformation) ([]plannedMove, error) {
+	game.addFullyRevealedLocations()
+	game.deduceUntilStuck(true)
+	if len(game.cellsToOpen) > 0 {
+		return game.takeQueuedCells(), nil
+	}
+	loc, risk, err := game.findBestGuess()
+	if err != nil {
+		return nil, err
+	}
+	return []plannedMove{{Cell: loc, Guess: true, Risk: risk}}, nil
+}
+
+// nextMovesOrGuess returns the queued safe cells, or the least risky cell if there are none.
+func (game *gameInformation) nextMovesOrGuess() ([]plannedMove, error) {
+	if len(game.cellsToOpen) > 0 {
+		return game.takeQueuedCells(), nil
+	}
+	loc, risk, err := game.findLeastRiskyCell()
+	if err != nil {
+		return nil, err
+	}
+	return []plannedMove{{Cell: loc, Guess: true, Risk: risk}}, nil
+}
+
+// takeQueuedCells empties the queue of cells known to be safe and returns them as moves.
+func (game *gameInformation) takeQueuedCells() []plannedMove {
+	moves := make([]plannedMove, 0, len(game.cellsToOpen))
+	for _, loc := range game.cellsToOpen {
+		moves = append(moves, plannedMove{Cell: loc})
+		// if the cell is still unknown the next time round, e.g. because the moves were dropped,
+		// the numbers around it have to find it again
+		game.markDirty(game.grid.offset(loc))
+	}
+	game.cellsToOpen = make([]location, 0)
+	game.queued.clear()
+	return moves
+}
+
+// findLeastRiskyCellByAddingRisks evaluates/intersects area of effect of numbered cells and tries
+// to guess which cells are more likely to contain a bomb. And, as a consequence, we get the
+// "least likely" cells. The sums aren't real probabilities (they can go above 1 where numbers
+// overlap), which is why the other strategies use findLeastRiskyCell instead.
+// The returned risk is that sum.
+func (game *gameInformation) findLeastRiskyCellByAddingRisks() (location, float64, error) {
+	g := &game.grid
+	probabilitiesOfBomb := make([]float64, len(g.cells))
+	candidates := newBitset(len(g.cells))
+
+	game.addFullyRevealedLocations()
+	for _, offset := range game.frontier.members() {
+		count, _ := g.number(offset)
+
+		visibleBombs := g.countAround(offset, gridBomb)
+		unknowns := g.countAround(offset, gridUnknown)
+		for _, n := range g.neighbours[offset] {
+			if g.cells[n] != gridUnknown {
+				continue
+			}
+			additionalRisk := float64(count-visibleBombs) / float64(unknowns)
+			probabilitiesOfBomb[n] += additionalRisk
+			candidates.add(n)
+		}
+	}
+
+	// find the cell with the lowest probability, the first one in the order of location.less
+	// if there's a tie
+	leastRiskyCell := -1
+	var leastRisk float64
+	for _, offset := range candidates.members() {
+		risk := probabilitiesOfBomb[offset]
+		if leastRiskyCell < 0 || risk < leastRisk {
+			leastRiskyCell = offset
+			leastRisk = risk
+		}
+	}
+	if leastRiskyCell >= 0 {
+		return g.location(leastRiskyCell), leastRisk, nil
+	}
+	return location{}, 0, fmt.Errorf("can't find least risky cell")
+}
@@ -116,10 +116,16 @@ func (a *DefaultApiService) MovePost(ctx context.Context, moveInfo MoveInfo) (Ga
 /* 
 DefaultApiService
  * @param ctx context.Context - for authentication, logging, cancellation, deadlines, tracing, etc. Passed from http.Request or context.Background().
+ * @param optional nil or map[string]interface{} with one or more of:
+     @param "seed" (int64) Seed for the board generator, the same seed gives the same board
+     @param "difficulty" (string) Named board size: beginner is 9x9 with 10 mines, intermediate 16x16 with 40, expert 30x16 with 99
+     @param "width" (int32) Board width, overrides the difficulty's
+     @param "height" (int32) Board height, overrides the difficulty's
+     @param "mines" (int32) Number of mines, overrides the difficulty's
 
 @return Game
 */
-func (a *DefaultApiService) NewgamePost(ctx context.Context) (Game, *http.Response, error) {
+func (a *DefaultApiService) NewgamePost(ctx context.Context, localVarOptionals map[string]interface{}) (Game, *http.Response, error) {
 	var (
 		localVarHttpMethod = strings.ToUpper("Post")
 		localVarPostBody   interface{}
@@ -135,6 +141,39 @@ func (a *DefaultApiService) NewgamePost(ctx context.Context) (Game, *http.Respon
 	localVarQueryParams := url.Values{}
 	localVarFormParams := url.Values{}
 
+	if err := typeCheckParameter(localVarOptionals["seed"], "int64", "seed"); err != nil {
+		return localVarReturnValue, nil, err
+	}
+
+	if err := typeCheckParameter(localVarOptionals["difficulty"], "string", "difficulty"); err != nil {
+		return localVarReturnValue, nil, err
+	}
+	if err := typeCheckParameter(localVarOptionals["width"], "int32", "width"); err != nil {
+		return localVarReturnValue, nil, err
+	}
+	if err := typeCheckParameter(localVarOptionals["height"], "int32", "height"); err != nil {
+		return localVarReturnValue, nil, err
+	}
+	if err := typeCheckParameter(localVarOptionals["mines"], "int32", "mines"); err != nil {
+		return localVarReturnValue, nil, err
+	}
+
+	if localVarTempParam, localVarOk := localVarOptionals["seed"].(int64); localVarOk {
+		localVarQueryParams.Add("seed", parameterToString(localVarTempParam, ""))
+	}
+	if localVarTempParam, localVarOk := localVarOptionals["difficulty"].(string); localVarOk {
+		localVarQueryParams.Add("difficulty", parameterToString(localVarTempParam, ""))
+	}
+	if localVarTempParam, localVarOk := localVarOptionals["width"].(int32); localVarOk {
+		localVarQueryParams.Add("width", parameterToString(localVarTempParam, ""))
+	}
+	if localVarTempParam, localVarOk := localVarOptionals["height"].(int32); localVarOk {
+		localVarQueryParams.Add("height", parameterToString(localVarTempParam, ""))
+	}
+	if localVarTempParam, localVarOk := localVarOptionals["mines"].(int32); localVarOk {
+		localVarQueryParams.Add("mines", parameterToString(localVarTempParam, ""))
+	}
+
 	// to determine the Content-Type header
 	localVarHttpContentTypes := []string{"application/json"}
 
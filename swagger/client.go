@@ -17,7 +17,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"math/rand"
 	"mime/multipart"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -143,9 +146,90 @@ func parameterToString(obj interface{}, collectionFormat string) string {
 	return fmt.Sprintf("%v", obj)
 }
 
-// callAPI do the request.
+// callAPI do the request, retrying failures that are safe to retry as configured.
 func (c *APIClient) callAPI(request *http.Request) (*http.Response, error) {
-	return c.cfg.HTTPClient.Do(request)
+	for attempt := 0; ; attempt++ {
+		response, err := c.callAPIOnce(request)
+		if attempt >= c.cfg.MaxRetries || !isRetryable(response, err) {
+			return response, err
+		}
+		if response != nil {
+			io.Copy(ioutil.Discard, response.Body)
+			response.Body.Close()
+		}
+
+		select {
+		case <-time.After(c.retryDelay(attempt)):
+		case <-request.Context().Done():
+			return nil, request.Context().Err()
+		}
+
+		// the body was consumed by the failed attempt
+		if request.GetBody != nil {
+			body, err := request.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			request.Body = body
+		}
+	}
+}
+
+// callAPIOnce makes a single attempt of the request within the configured timeout.
+func (c *APIClient) callAPIOnce(request *http.Request) (*http.Response, error) {
+	if c.cfg.Timeout <= 0 {
+		return c.cfg.HTTPClient.Do(request)
+	}
+
+	ctx, cancel := context.WithTimeout(request.Context(), c.cfg.Timeout)
+	response, err := c.cfg.HTTPClient.Do(request.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return response, err
+	}
+	// the timeout covers reading the body too, so it ends when the body is closed
+	response.Body = cancelOnClose{ReadCloser: response.Body, cancel: cancel}
+	return response, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// isRetryable tells if a request failed in a way that guarantees it wasn't processed, so that
+// sending it again is safe even when it isn't idempotent.
+func isRetryable(response *http.Response, err error) bool {
+	if err != nil {
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		// failing to connect at all, e.g. connection refused
+		opErr, ok := err.(*net.OpError)
+		return ok && opErr.Op == "dial"
+	}
+	return response.StatusCode == http.StatusBadGateway || response.StatusCode == http.StatusServiceUnavailable
+}
+
+// retryDelay is the exponential backoff before retry number attempt+1, with half of it random.
+func (c *APIClient) retryDelay(attempt int) time.Duration {
+	delay := c.cfg.RetryBackoff
+	for i := 0; i < attempt && (c.cfg.MaxRetryBackoff <= 0 || delay < c.cfg.MaxRetryBackoff); i++ {
+		delay *= 2
+	}
+	if c.cfg.MaxRetryBackoff > 0 && delay > c.cfg.MaxRetryBackoff {
+		delay = c.cfg.MaxRetryBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
 }
 
 // Change base path to allow switching to mocks
@@ -0,0 +1,156 @@
+package swagger
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer answers every request with the status the respond function picks for its attempt,
+// counting from 0, and records the bodies it got.
+type testServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	bodies []string
+}
+
+func newTestServer(respond func(attempt int, w http.ResponseWriter, r *http.Request)) *testServer {
+	s := &testServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		s.mu.Lock()
+		attempt := len(s.bodies)
+		s.bodies = append(s.bodies, string(body))
+		s.mu.Unlock()
+		respond(attempt, w, r)
+	}))
+	return s
+}
+
+func (s *testServer) attempts() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.bodies...)
+}
+
+func newTestClient(timeout time.Duration, maxRetries int, backoff time.Duration) *APIClient {
+	cfg := NewConfiguration()
+	cfg.Timeout = timeout
+	cfg.MaxRetries = maxRetries
+	cfg.RetryBackoff = backoff
+	cfg.MaxRetryBackoff = backoff
+	return NewAPIClient(cfg)
+}
+
+func post(t *testing.T, ctx context.Context, url string) *http.Request {
+	request, err := http.NewRequest(http.MethodPost, url, strings.NewReader(`{"x":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return request.WithContext(ctx)
+}
+
+func TestCallAPIRetriesUnavailable(t *testing.T) {
+	server := newTestServer(func(attempt int, w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer server.Close()
+
+	response, err := newTestClient(time.Second, 2, time.Millisecond).callAPI(post(t, context.Background(), server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status is %d, want %d once the retries run out", response.StatusCode, http.StatusServiceUnavailable)
+	}
+	attempts := server.attempts()
+	if len(attempts) != 3 {
+		t.Fatalf("%d attempts, want 3", len(attempts))
+	}
+	for i, body := range attempts {
+		if body != `{"x":1}` {
+			t.Errorf("attempt %d sent %q", i, body)
+		}
+	}
+}
+
+func TestCallAPIRecoversAfterRetry(t *testing.T) {
+	server := newTestServer(func(attempt int, w http.ResponseWriter, r *http.Request) {
+		if attempt == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	defer server.Close()
+
+	response, err := newTestClient(time.Second, 3, time.Millisecond).callAPI(post(t, context.Background(), server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK || len(server.attempts()) != 2 {
+		t.Errorf("status %d after %d attempts, want %d after 2", response.StatusCode, len(server.attempts()), http.StatusOK)
+	}
+}
+
+// A request that timed out or failed on the server may have been processed, so it isn't sent again.
+func TestCallAPIDoesNotRetryUnsafeFailures(t *testing.T) {
+	t.Run("timeout", func(t *testing.T) {
+		server := newTestServer(func(attempt int, w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		})
+		defer server.Close()
+
+		if _, err := newTestClient(20*time.Millisecond, 3, time.Millisecond).callAPI(post(t, context.Background(), server.URL)); err == nil {
+			t.Errorf("no error for a request that timed out")
+		}
+		if attempts := len(server.attempts()); attempts != 1 {
+			t.Errorf("%d attempts, want 1", attempts)
+		}
+	})
+
+	t.Run("internal server error", func(t *testing.T) {
+		server := newTestServer(func(attempt int, w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		defer server.Close()
+
+		response, err := newTestClient(time.Second, 3, time.Millisecond).callAPI(post(t, context.Background(), server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		if attempts := len(server.attempts()); response.StatusCode != http.StatusInternalServerError || attempts != 1 {
+			t.Errorf("status %d after %d attempts, want %d after 1", response.StatusCode, attempts, http.StatusInternalServerError)
+		}
+	})
+}
+
+func TestCallAPIStopsRetryingWhenCancelled(t *testing.T) {
+	server := newTestServer(func(attempt int, w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := newTestClient(time.Second, 3, time.Minute).callAPI(post(t, ctx, server.URL))
+	if err != context.DeadlineExceeded {
+		t.Errorf("error is %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("took %v, the backoff wasn't cut short", elapsed)
+	}
+	if attempts := len(server.attempts()); attempts != 1 {
+		t.Errorf("%d attempts, want 1", attempts)
+	}
+}
@@ -11,6 +11,7 @@ package swagger
 
 import (
 	"net/http"
+	"time"
 )
 
 // contextKeys are used to identify the type of value in the context.
@@ -56,13 +57,27 @@ type Configuration struct {
 	DefaultHeader map[string]string `json:"defaultHeader,omitempty"`
 	UserAgent     string            `json:"userAgent,omitempty"`
 	HTTPClient    *http.Client
+
+	// Timeout limits each attempt of a request, reading the response body included. Zero means no limit.
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// MaxRetries is how many times a request is retried after a failure that's safe to retry:
+	// the connection being refused, or a 502 or 503 response. Zero disables retries.
+	MaxRetries int `json:"maxRetries,omitempty"`
+	// RetryBackoff is the delay before the first retry. Every next retry waits twice as long, up to
+	// MaxRetryBackoff. Delays are randomized, so that many clients don't retry all at once.
+	RetryBackoff    time.Duration `json:"retryBackoff,omitempty"`
+	MaxRetryBackoff time.Duration `json:"maxRetryBackoff,omitempty"`
 }
 
 func NewConfiguration() *Configuration {
 	cfg := &Configuration{
-		BasePath:      "http://minesweeper.tulentsev.com",
-		DefaultHeader: make(map[string]string),
-		UserAgent:     "Swagger-Codegen/1.0.0/go",
+		BasePath:        "http://minesweeper.tulentsev.com",
+		DefaultHeader:   make(map[string]string),
+		UserAgent:       "Swagger-Codegen/1.0.0/go",
+		Timeout:         30 * time.Second,
+		MaxRetries:      3,
+		RetryBackoff:    100 * time.Millisecond,
+		MaxRetryBackoff: 5 * time.Second,
 	}
 	return cfg
 }
@@ -17,4 +17,5 @@ type Game struct {
 	MinesCount int32 `json:"mines_count,omitempty"`
 	BoardState []string `json:"board_state,omitempty"`
 	PrettyBoardState string `json:"pretty_board_state,omitempty"`
+	Seed int64 `json:"seed,omitempty"`
 }
@@ -13,4 +13,14 @@ type MoveInfo struct {
 	GameId string `json:"game_id"`
 	X int32 `json:"x"`
 	Y int32 `json:"y"`
+	// What to do with the cell: open it, flag or unflag a hidden cell, or chord (open every unflagged neighbour of an open cell that has as many flags around as its number)
+	Action string `json:"action,omitempty"`
 }
+
+// Values of MoveInfo.Action. An empty Action opens the cell.
+const (
+	MoveActionOpen   = "open"
+	MoveActionFlag   = "flag"
+	MoveActionUnflag = "unflag"
+	MoveActionChord  = "chord"
+)
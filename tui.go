@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"minesweeper-bot/swagger"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	minWatchDelay = 10 * time.Millisecond
+	maxWatchDelay = 5 * time.Second
+)
+
+func watchCommand(args []string) error {
+	flags := newFlagSet("watch")
+	backendOptions := addBackendFlags(flags)
+	boardOptions := addBoardFlags(flags)
+	strategyName := flags.String("strategy", defaultStrategyName, fmt.Sprintf("solver to play with, one of %v", strategyNames()))
+	seed := flags.Int64("seed", time.Now().UnixNano(), "seed of the first game, every new game takes the next one")
+	delay := flags.Duration("delay", 300*time.Millisecond, "pause between moves, change it with + and - while watching")
+	paused := flags.Bool("paused", false, "start paused, step with n")
+	openingText := flags.String("opening", openingCentre, openingUsage)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	first, err := parseOpening(*openingText)
+	if err != nil {
+		return usageError{err.Error()}
+	}
+	if *delay < minWatchDelay || *delay > maxWatchDelay {
+		return usageError{fmt.Sprintf("-delay must be between %v and %v", minWatchDelay, maxWatchDelay)}
+	}
+	backend, err := backendOptions.newBackend()
+	if err != nil {
+		return err
+	}
+	board, err := boardOptions.spec()
+	if err != nil {
+		return err
+	}
+	if err := backendOptions.checkOpening(first, board); err != nil {
+		return err
+	}
+	strategy, err := strategyByName(*strategyName)
+	if err != nil {
+		return usageError{err.Error()}
+	}
+
+	restoreTerminal, err := enableRawMode()
+	if err != nil {
+		return fmt.Errorf("watch needs a terminal: %v", err)
+	}
+	// alternate screen, hidden cursor
+	fmt.Print("\u001b[?1049h\u001b[?25l")
+	var once sync.Once
+	leaveScreen := func() {
+		once.Do(func() {
+			fmt.Print("\u001b[?25h\u001b[?1049l")
+			restoreTerminal()
+		})
+	}
+	defer leaveScreen()
+
+	// a second interrupt exits on the spot, and mustn't leave the terminal in cbreak mode
+	ctx, stop := interruptibleContext("the game in progress is abandoned", leaveScreen)
+	defer stop()
+	w := &watcher{
+		backend:  backend,
+		strategy: strategy,
+		seed:     *seed,
+		board:    board,
+		opening:  first,
+		delay:    *delay,
+		paused:   *paused,
+		out:      os.Stdout,
+	}
+	return w.run(ctx, readKeys(os.Stdin))
+}
+
+// enableRawMode switches the terminal on stdin to reading key by key without echo, and returns
+// a function that puts it back the way it was. It shells out to stty, so there's nothing
+// platform specific to build.
+func enableRawMode() (restore func(), err error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("cbreak", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() {
+		_, _ = stty(strings.TrimSpace(saved))
+	}, nil
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// readKeys sends every byte read from r to the returned channel, and closes it when r ends.
+func readKeys(r io.Reader) <-chan byte {
+	keys := make(chan byte)
+	go func() {
+		defer close(keys)
+		buf := make([]byte, 1)
+		for {
+			if _, err := r.Read(buf); err != nil {
+				return
+			}
+			keys <- buf[0]
+		}
+	}()
+	return keys
+}
+
+// watcher plays games one move at a time on screen. A step either asks the strategy for its
+// next moves, or opens the first of the moves it planned, so the cell about to be opened is
+// always highlighted before it is.
+type watcher struct {
+	backend  gameBackend
+	strategy Strategy
+	seed     int64
+	board    boardSpec
+	opening  opening
+	out      io.Writer
+
+	game    gameInformation
+	started bool
+	pending []plannedMove
+
+	paused            bool
+	delay             time.Duration
+	showProbabilities bool
+	// input is what's typed so far of a human move, nil when not taking one
+	input   *string
+	message string
+}
+
+func (w *watcher) run(ctx context.Context, keys <-chan byte) error {
+	if err := w.newGame(ctx); err != nil {
+		return err
+	}
+	for {
+		w.render()
+		var tick <-chan time.Time
+		if !w.paused && w.input == nil && !w.game.IsFinished() {
+			tick = time.After(w.delay)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			quit, err := w.handleKey(ctx, key)
+			if err != nil || quit {
+				return err
+			}
+		case <-tick:
+			w.step(ctx)
+		}
+	}
+}
+
+func (w *watcher) newGame(ctx context.Context) error {
+	if w.started {
+		w.seed++
+	}
+	initialGame, err := w.backend.NewGame(ctx, newGameRequest{Seed: w.seed, Board: w.board})
+	if err != nil {
+		return err
+	}
+	w.started = true
+	w.game = newGameInfo(initialGame)
+	w.game.seed = w.seed
+	if w.pending, err = openingMoves(&w.game, w.opening, w.backend.FirstClick()); err != nil {
+		return err
+	}
+	w.message = fmt.Sprintf("new game, seed %d", w.seed)
+	return nil
+}
+
+func (w *watcher) handleKey(ctx context.Context, key byte) (quit bool, err error) {
+	if w.input != nil {
+		w.editInput(ctx, key)
+		return false, nil
+	}
+	switch key {
+	case 'q':
+		return true, nil
+	case ' ':
+		w.paused = !w.paused
+	case 'n':
+		w.paused = true
+		w.step(ctx)
+	case '+':
+		if w.delay /= 2; w.delay < minWatchDelay {
+			w.delay = minWatchDelay
+		}
+	case '-':
+		if w.delay *= 2; w.delay > maxWatchDelay {
+			w.delay = maxWatchDelay
+		}
+	case 'p':
+		w.showProbabilities = !w.showProbabilities
+	case 'o':
+		if !w.game.IsFinished() {
+			input := ""
+			w.input = &input
+		}
+	case 'r':
+		if err := w.newGame(ctx); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// editInput takes one key of a human move: "x y" or "x,y", then Enter. Escape gives up.
+func (w *watcher) editInput(ctx context.Context, key byte) {
+	switch {
+	case key == '\n' || key == '\r':
+		typed := *w.input
+		w.input = nil
+		cell, err := parseCell(typed)
+		if err != nil {
+			w.message = err.Error()
+			return
+		}
+		w.open(ctx, plannedMove{Cell: cell}, "you")
+		// the strategy planned for a board that's changed since
+		w.pending = nil
+	case key == 0x1b:
+		w.input = nil
+	case key == 0x7f || key == '\b':
+		if len(*w.input) > 0 {
+			*w.input = (*w.input)[:len(*w.input)-1]
+		}
+	case key >= '0' && key <= '9' || key == ' ' || key == ',':
+		*w.input += string(key)
+	}
+}
+
+func parseCell(text string) (location, error) {
+	fields := strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == ',' })
+	if len(fields) != 2 {
+		return location{}, fmt.Errorf("expected a cell as \"x y\", got %q", text)
+	}
+	x, errX := strconv.Atoi(fields[0])
+	y, errY := strconv.Atoi(fields[1])
+	if errX != nil || errY != nil {
+		return location{}, fmt.Errorf("expected a cell as \"x y\", got %q", text)
+	}
+	return location{x, y}, nil
+}
+
+// step opens the next planned cell, or plans the next moves if there are none left.
+func (w *watcher) step(ctx context.Context) {
+	if w.game.IsFinished() {
+		return
+	}
+	for len(w.pending) > 0 && !w.game.isUnknown(w.pending[0].Cell) {
+		w.pending = w.pending[1:]
+	}
+	if len(w.pending) == 0 {
+		moves, err := w.strategy.NextMoves(&w.game)
+		if err != nil {
+			w.paused = true
+			w.message = fmt.Sprintf("the strategy is stuck (%v), open a cell yourself with o", err)
+			return
+		}
+		w.pending = moves
+		w.message = fmt.Sprintf("planned %d moves", len(moves))
+		return
+	}
+
+	planned := w.pending[0]
+	w.pending = w.pending[1:]
+	by := "safe"
+	if planned.Guess {
+		by = fmt.Sprintf("guess, %.1f%% risk", planned.Risk*100)
+	} else if w.game.movesMade == 0 {
+		by = "opening"
+	}
+	w.open(ctx, planned, by)
+}
+
+func (w *watcher) open(ctx context.Context, planned plannedMove, by string) {
+	cell := planned.Cell
+	if !w.game.grid.contains(cell) {
+		w.message = fmt.Sprintf("(%d, %d) is off the board", cell.X, cell.Y)
+		return
+	}
+	if !w.game.isUnknown(cell) {
+		w.message = fmt.Sprintf("(%d, %d) is already open", cell.X, cell.Y)
+		return
+	}
+	moveInfo := swagger.MoveInfo{GameId: w.game.GameId, X: int32(cell.X), Y: int32(cell.Y)}
+	newGameState, err := move(ctx, w.backend, moveInfo)
+	if err != nil {
+		w.paused = true
+		w.message = fmt.Sprintf("can't open (%d, %d): %v", cell.X, cell.Y, err)
+		return
+	}
+	w.game.receive(newGameState)
+	w.game.movesMade++
+	if planned.Guess {
+		w.game.guessesTaken++
+	}
+	w.message = fmt.Sprintf("opened (%d, %d): %s", cell.X, cell.Y, by)
+	if w.game.IsFinished() {
+		w.message += fmt.Sprintf(". Game over: %s, r for a new game", w.game.Status)
+	}
+}
+
+// next is the cell the next step opens, if it's known yet.
+func (w *watcher) next() (location, bool) {
+	for _, planned := range w.pending {
+		if w.game.isUnknown(planned.Cell) {
+			return planned.Cell, true
+		}
+	}
+	return location{}, false
+}
+
+func (w *watcher) render() {
+	var screen bytes.Buffer
+	// home, clear screen
+	screen.WriteString("\u001b[H\u001b[J")
+
+	state := "running"
+	if w.paused {
+		state = "paused"
+	}
+	fmt.Fprintf(&screen, "game %s, seed %d, %d mines; %s, %v per move\n", w.game.GameId, w.seed, w.game.MinesCount, state, w.delay)
+	fmt.Fprintf(&screen, "moves %d, guesses %d, bombs found %d\n\n", w.game.movesMade, w.game.guessesTaken, w.game.bombs.count())
+
+	next, hasNext := w.next()
+	safe := make(map[location]bool)
+	for _, planned := range w.pending {
+		if !planned.Guess {
+			safe[planned.Cell] = true
+		}
+	}
+	var probabilities []float64
+	if w.showProbabilities && !w.game.IsFinished() && w.game.movesMade > 0 {
+		probabilities = w.game.mineProbabilities()
+	}
+	printAnnotatedBoardState(&screen, w.game, func(loc location, cellState string) string {
+		text := colored(cellState)
+		if probabilities != nil && cellState == "?" && probabilities[w.game.grid.offset(loc)] != noProbability {
+			text = probabilityCell(probabilities[w.game.grid.offset(loc)], safe[loc])
+		}
+		if hasNext && loc == next {
+			// reverse video, without the colour codes so it stands out
+			text = fmt.Sprintf("\u001b[7m%s\u001b[0m", cellState)
+		}
+		return text
+	})
+
+	screen.WriteString("\n")
+	if w.input != nil {
+		fmt.Fprintf(&screen, "open cell (x y), Enter to open, Esc to cancel: %s\n", *w.input)
+	} else {
+		fmt.Fprintln(&screen, w.message)
+	}
+	fmt.Fprintln(&screen, "space pause/resume, n step, +/- speed, p probabilities, o open a cell yourself, r new game, q quit")
+	if probabilities != nil {
+		fmt.Fprintln(&screen, "probabilities: s safe, 0-9 tens of percent of a mine, m certain mine")
+	}
+	_, _ = screen.WriteTo(w.out)
+}
+
+// probabilityCell draws the chance of a mine in an unknown cell as a single character.
+func probabilityCell(probability float64, knownSafe bool) string {
+	switch {
+	case knownSafe || probability < certaintyEpsilon:
+		return "\u001b[32ms\u001b[0m"
+	case probability > 1-certaintyEpsilon:
+		return "\u001b[31mm\u001b[0m"
+	}
+	tens := int(probability * 10)
+	if tens > 9 {
+		tens = 9
+	}
+	// from yellow to red as the risk goes up
+	colors := []int{229, 228, 227, 226, 220, 214, 208, 202, 196, 160}
+	return fmt.Sprintf("\u001b[38;5;%dm%d\u001b[0m", colors[tens], tens)
+}